@@ -0,0 +1,110 @@
+package remotewrite
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// basicAuth holds the credentials set by WithBasicAuth.
+type basicAuth struct {
+	username string
+	password string
+}
+
+// WithBasicAuth authenticates every request with HTTP basic auth.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.basicAuth = &basicAuth{username: username, password: password}
+	}
+}
+
+// WithBearerToken authenticates every request with a static bearer token.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearer = &bearerToken{token: token}
+	}
+}
+
+// WithBearerTokenFile authenticates every request with the bearer token
+// stored in path. The file is read again whenever it changes, so rotated
+// tokens are picked up without a restart.
+func WithBearerTokenFile(path string) Option {
+	return func(c *Client) {
+		c.bearer = &bearerToken{path: path}
+	}
+}
+
+// bearerToken is a static token or one read from a file.
+type bearerToken struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// get returns the current token, reloading it from the file if it changed.
+func (b *bearerToken) get() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.path == "" {
+		return b.token, nil
+	}
+
+	fi, err := os.Stat(b.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat bearer token file: %w", err)
+	}
+	if b.token != "" && fi.ModTime().Equal(b.modTime) && fi.Size() == b.size {
+		return b.token, nil
+	}
+
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bearer token file: %w", err)
+	}
+	b.token = strings.TrimSpace(string(data))
+	b.modTime = fi.ModTime()
+	b.size = fi.Size()
+
+	return b.token, nil
+}
+
+// authorize adds the configured credentials to req.
+func (c *Client) authorize(req *http.Request) error {
+	switch {
+	case c.basicAuth != nil:
+		req.SetBasicAuth(c.basicAuth.username, c.basicAuth.password)
+	case c.bearer != nil:
+		token, err := c.bearer.get()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// authSchemes returns the number of authentication schemes configured.
+func (c *Client) authSchemes() int {
+	n := 0
+	if c.basicAuth != nil {
+		n++
+	}
+	if c.bearer != nil {
+		n++
+	}
+	if c.signer != nil {
+		n++
+	}
+	if c.oauth2 != nil {
+		n++
+	}
+	return n
+}
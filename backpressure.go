@@ -0,0 +1,240 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// BackpressurePolicy is what Run does when a send takes longer than the
+// frequency interval.
+type BackpressurePolicy int
+
+const (
+	// BackpressureSkip skips the ticks that fire while a send is running.
+	// Nothing is gathered for them. This is the default.
+	BackpressureSkip BackpressurePolicy = iota
+	// BackpressureQueue keeps gathering on every tick and queues the
+	// results for a single sender, which sends them in order. While Run
+	// is running, WriteOnce and Flush queue their gather too and wait for
+	// it to be sent. When the queue is full the oldest queued gather is
+	// dropped. When Run stops, the queued gathers are sent if
+	// WithFlushOnShutdown is set and dropped otherwise.
+	BackpressureQueue
+)
+
+// WithBackpressure sets how Run handles sends that overrun the frequency
+// interval. maxQueued bounds the number of gathers waiting to be sent with
+// BackpressureQueue and is ignored otherwise. Skipped ticks are counted in
+// remote_write_missed_ticks_total and dropped gathers in
+// remote_write_queue_dropped_total.
+func WithBackpressure(policy BackpressurePolicy, maxQueued int) Option {
+	return func(c *Client) {
+		c.backpressure = policy
+		c.maxQueued = maxQueued
+	}
+}
+
+// WithMaxBufferBytes bounds the encoded size of the gathers waiting in the
+// send queue of BackpressureQueue to n bytes, on top of the number bounded by
+// WithBackpressure, so that a long outage of the endpoint cannot exhaust
+// memory. It requires BackpressureQueue and does not cover the state kept per
+// series, such as that of WithSeriesTTL or WithSkipDuplicateSamples. When a
+// new gather does not fit, the oldest queued gathers are dropped and counted
+// in remote_write_queue_dropped_total; the new gather is always queued. Zero,
+// the default, means no limit.
+func WithMaxBufferBytes(n int64) Option {
+	return func(c *Client) {
+		c.maxBufferBytes = n
+	}
+}
+
+// sendQueue holds gathers waiting to be sent by a single goroutine.
+type sendQueue struct {
+	c       *Client
+	batches chan queuedGather
+	done    chan struct{}
+	report  func(error)
+
+	// bytes is the size of the queued gathers.
+	bytes atomic.Int64
+}
+
+// queuedGather is a gather waiting in the send queue. result is set for
+// gathers queued by WriteOnce, which waits for their send.
+type queuedGather struct {
+	series []prompb.TimeSeries
+	size   int64
+	result chan error
+}
+
+// newQueuedGather returns series as a queuedGather, recording its size.
+func newQueuedGather(series []prompb.TimeSeries, result chan error) queuedGather {
+	g := queuedGather{series: series, result: result}
+	for i := range series {
+		g.size += int64(series[i].Size())
+	}
+	return g
+}
+
+var (
+	errGatherDropped = errors.New("gather dropped because the send queue was full")
+	errQueueStopped  = errors.New("send queue stopped before the gather was sent")
+)
+
+// startQueue starts a sender that runs until ctx is cancelled and makes
+// WriteOnce queue its gathers. The outcome of every send of a tick is passed
+// to report.
+func (c *Client) startQueue(ctx context.Context, report func(error)) *sendQueue {
+	q := &sendQueue{
+		c:       c,
+		batches: make(chan queuedGather, c.maxQueued),
+		done:    make(chan struct{}),
+		report:  report,
+	}
+	go q.run(ctx)
+
+	c.writeMu.Lock()
+	c.queue = q
+	c.writeMu.Unlock()
+	return q
+}
+
+func (q *sendQueue) run(ctx context.Context) {
+	defer close(q.done)
+
+	for {
+		select {
+		case <-ctx.Done():
+			q.drain(ctx, nil)
+			return
+		case g := <-q.batches:
+			q.bytes.Add(-g.size)
+			q.c.metrics.queueLength.Set(float64(len(q.batches)))
+			if ctx.Err() != nil {
+				q.drain(ctx, []queuedGather{g})
+				return
+			}
+
+			q.c.sendMu.Lock()
+			err := q.c.sendAll(ctx, g.series)
+			q.c.sendMu.Unlock()
+			if g.result != nil {
+				g.result <- err
+				continue
+			}
+			if ctx.Err() != nil {
+				continue
+			}
+			if err != nil {
+				q.c.logSendError(err)
+			}
+			q.report(err)
+		}
+	}
+}
+
+// drain sends pending and the gathers still queued once ctx is cancelled,
+// allowing them the timeout of WithFlushOnShutdown. Without it they are not
+// sent, and their waiters are released when the sender exits.
+func (q *sendQueue) drain(ctx context.Context, pending []queuedGather) {
+	for len(q.batches) > 0 {
+		select {
+		case g := <-q.batches:
+			q.bytes.Add(-g.size)
+			pending = append(pending, g)
+		default:
+		}
+	}
+	q.c.metrics.queueLength.Set(0)
+
+	if q.c.flushTimeout <= 0 || len(pending) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.c.flushTimeout)
+	defer cancel()
+
+	for _, g := range pending {
+		q.c.sendMu.Lock()
+		err := q.c.sendAll(ctx, g.series)
+		q.c.sendMu.Unlock()
+		if g.result != nil {
+			g.result <- err
+		} else if err != nil {
+			q.c.logSendError(err)
+		}
+	}
+}
+
+// push queues g, dropping the oldest queued gathers if the queue is full or
+// g does not fit its byte budget.
+func (q *sendQueue) push(g queuedGather) {
+	if max := q.c.maxBufferBytes; max > 0 {
+		for len(q.batches) > 0 && q.bytes.Load()+g.size > max {
+			select {
+			case old := <-q.batches:
+				q.drop(old)
+			default:
+			}
+		}
+	}
+	q.bytes.Add(g.size)
+
+	for {
+		select {
+		case q.batches <- g:
+			q.c.metrics.queueLength.Set(float64(len(q.batches)))
+			return
+		default:
+		}
+
+		select {
+		case old := <-q.batches:
+			q.drop(old)
+		default:
+		}
+	}
+}
+
+// drop discards a gather taken from the queue.
+func (q *sendQueue) drop(g queuedGather) {
+	q.bytes.Add(-g.size)
+	if g.result != nil {
+		g.result <- errGatherDropped
+	}
+	q.c.metrics.queueDropped.Inc()
+	q.c.logger.Warn("send queue full, dropping oldest gather")
+}
+
+// wait waits for the send of a gather queued with result.
+func (q *sendQueue) wait(ctx context.Context, result chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.done:
+		select {
+		case err := <-result:
+			return err
+		default:
+			return errQueueStopped
+		}
+	}
+}
+
+// stop waits for the sender to exit after its context is cancelled, after
+// which WriteOnce sends directly again. It does nothing for a nil queue.
+func (q *sendQueue) stop() {
+	if q == nil {
+		return
+	}
+	<-q.done
+
+	q.c.writeMu.Lock()
+	q.c.queue = nil
+	q.c.writeMu.Unlock()
+}
@@ -0,0 +1,254 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// BatchSplitter splits the series produced by a single gather into batches.
+// Each batch is sent as a separate request.
+type BatchSplitter interface {
+	Split(series []prompb.TimeSeries) [][]prompb.TimeSeries
+}
+
+// BatchSplitterFunc adapts an ordinary function to a BatchSplitter.
+type BatchSplitterFunc func(series []prompb.TimeSeries) [][]prompb.TimeSeries
+
+// Split calls f(series).
+func (f BatchSplitterFunc) Split(series []prompb.TimeSeries) [][]prompb.TimeSeries {
+	return f(series)
+}
+
+// WithBatchSplitter sets how series are split into requests. The default is
+// NoSplit.
+func WithBatchSplitter(s BatchSplitter) Option {
+	return func(c *Client) {
+		c.splitter = s
+	}
+}
+
+// WithAdaptiveBatching makes the Client recover from 413 Payload Too Large
+// responses by halving the number of series in the rejected batch and
+// retrying. The reduced size is remembered and applied to later batches. A
+// batch is never split below minSeries series; the 413 is returned instead.
+func WithAdaptiveBatching(minSeries int) Option {
+	return func(c *Client) {
+		c.adaptiveBatching = true
+		c.minBatchSeries = minSeries
+	}
+}
+
+// WithMaxBatchBytes caps the compressed size of each request body at n bytes.
+// A batch whose encoded payload exceeds n is split in half at series
+// boundaries until every part fits; a single series larger than n is sent on
+// its own. It applies on top of the configured BatchSplitter.
+func WithMaxBatchBytes(n int) Option {
+	return func(c *Client) {
+		c.maxBatchBytes = n
+	}
+}
+
+// sendBatch sends series as one or more requests, splitting it when it
+// exceeds the batch limits. Every part is sent even if an earlier one fails,
+// and their errors are joined. It waits and retries when the endpoint rate
+// limits with a Retry-After header, for at most the send interval in total,
+// and shrinks the batch size when the endpoint reports the payload as too
+// large.
+func (c *Client) sendBatch(ctx context.Context, series []prompb.TimeSeries, tenant string) error {
+	if limit := c.currentBatchLimit(); limit > 0 && len(series) > limit {
+		var errs []error
+		for _, batch := range SplitBySeries(limit).Split(series) {
+			errs = append(errs, c.sendBatch(ctx, batch, tenant))
+		}
+		return errors.Join(errs...)
+	}
+
+	p, err := c.encode(&prompb.WriteRequest{Timeseries: series}, tenant)
+	if err != nil {
+		return err
+	}
+	if c.maxBatchBytes > 0 && len(p.Body) > c.maxBatchBytes && len(series) > 1 {
+		// Both halves are sent even if the first fails, so that each
+		// is spooled or dead-lettered on its own.
+		half := len(series) / 2
+		return errors.Join(
+			c.sendBatch(ctx, series[:half:half], tenant),
+			c.sendBatch(ctx, series[half:], tenant),
+		)
+	}
+
+	c.metrics.payloadBytes.WithLabelValues(c.endpointName).Observe(float64(len(p.Body)))
+
+	err = c.attempt(ctx, p, len(series))
+	budget := c.retryAfterBudget()
+	for i := 0; i < maxRateLimitRetries; i++ {
+		d := retryAfter(err)
+		if d <= 0 {
+			break
+		}
+		if d > budget {
+			c.logger.Warn("rate limited by remote write endpoint, not waiting for Retry-After", "after", d, "limit", budget)
+			break
+		}
+		budget -= d
+		c.logger.Warn("rate limited by remote write endpoint, retrying", "after", d)
+		if !sleep(ctx, d) {
+			break
+		}
+		err = c.attempt(ctx, p, len(series))
+	}
+	if err == nil {
+		c.metrics.samplesSent.WithLabelValues(c.endpointName).Add(float64(sampleCount(series)))
+		c.metrics.lastSuccess.WithLabelValues(c.endpointName).SetToCurrentTime()
+		return nil
+	}
+
+	code := statusCode(err)
+	if c.adaptiveBatching && code == http.StatusRequestEntityTooLarge {
+		if half := len(series) / 2; half >= c.minBatchSeries && half > 0 {
+			c.reduceBatchLimit(half)
+			return c.sendBatch(ctx, series, tenant)
+		}
+	}
+
+	if c.deadLetter != nil && isPermanent(code) {
+		c.deadLetter(p.Body, code, err.Error())
+	}
+
+	spooled := false
+	if c.spool != nil && !isPermanent(code) {
+		if err := c.store(p, len(series)); err != nil {
+			c.logger.Error("failed to spool payload", "err", err)
+		} else {
+			spooled = true
+		}
+	}
+	if !spooled {
+		c.forgetUnsent(series)
+	}
+
+	c.metrics.sendsFailed.WithLabelValues(c.endpointName).Inc()
+	return err
+}
+
+// sampleCount returns the number of float and histogram samples in series.
+func sampleCount(series []prompb.TimeSeries) int {
+	var n int
+	for _, ts := range series {
+		n += len(ts.Samples) + len(ts.Histograms)
+	}
+	return n
+}
+
+func (c *Client) currentBatchLimit() int {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	return c.batchLimit
+}
+
+// reduceBatchLimit lowers the adaptive batch size to n unless a concurrent
+// send already lowered it further.
+func (c *Client) reduceBatchLimit(n int) {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+
+	if c.batchLimit == 0 || n < c.batchLimit {
+		c.batchLimit = n
+		c.logger.Warn("payload too large, reducing batch size", "series", n)
+	}
+}
+
+// NoSplit sends all series in a single request.
+func NoSplit() BatchSplitter {
+	return BatchSplitterFunc(func(series []prompb.TimeSeries) [][]prompb.TimeSeries {
+		return [][]prompb.TimeSeries{series}
+	})
+}
+
+// SplitBySeries splits series into batches of at most n series.
+func SplitBySeries(n int) BatchSplitter {
+	return BatchSplitterFunc(func(series []prompb.TimeSeries) [][]prompb.TimeSeries {
+		if n <= 0 {
+			return NoSplit().Split(series)
+		}
+
+		var batches [][]prompb.TimeSeries
+		for len(series) > n {
+			batches = append(batches, series[:n:n])
+			series = series[n:]
+		}
+		if len(series) > 0 {
+			batches = append(batches, series)
+		}
+		return batches
+	})
+}
+
+// SplitByBytes splits series into batches whose uncompressed protobuf size
+// stays under n bytes. A single series larger than n is sent on its own.
+func SplitByBytes(n int) BatchSplitter {
+	return BatchSplitterFunc(func(series []prompb.TimeSeries) [][]prompb.TimeSeries {
+		if n <= 0 {
+			return NoSplit().Split(series)
+		}
+
+		var (
+			batches [][]prompb.TimeSeries
+			start   int
+			size    int
+		)
+		for i := range series {
+			s := series[i].Size()
+			if size+s > n && i > start {
+				batches = append(batches, series[start:i:i])
+				start, size = i, 0
+			}
+			size += s
+		}
+		if start < len(series) {
+			batches = append(batches, series[start:])
+		}
+		return batches
+	})
+}
+
+// SplitByMetricName sends the series of each metric name in a separate
+// request.
+func SplitByMetricName() BatchSplitter {
+	return SplitByLabel("__name__")
+}
+
+// SplitByLabel groups series by the value of the label name, such as a tenant
+// label, and sends each group in a separate request. Series without the label
+// form their own group. Groups are ordered by first appearance.
+func SplitByLabel(name string) BatchSplitter {
+	return BatchSplitterFunc(func(series []prompb.TimeSeries) [][]prompb.TimeSeries {
+		var (
+			batches [][]prompb.TimeSeries
+			index   = map[string]int{}
+		)
+		for _, ts := range series {
+			v := labelValue(ts.Labels, name)
+			i, ok := index[v]
+			if !ok {
+				i = len(batches)
+				index[v] = i
+				batches = append(batches, nil)
+			}
+			batches[i] = append(batches[i], ts)
+		}
+		return batches
+	})
+}
+
+func labelValue(labels []prompb.Label, name string) string {
+	for _, l := range labels {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package remotewrite
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for sends skipped because the circuit breaker
+// set by WithCircuitBreaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open, send skipped")
+
+// Circuit breaker states, as reported by remote_write_circuit_breaker_state.
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// WithCircuitBreaker stops sending to the endpoint after failures
+// consecutive failed sends. While the circuit is open, sends fail with
+// ErrCircuitOpen without contacting the endpoint and their batches are
+// dropped, or spooled if WithSpool is set. After cooldown, the next send is
+// let through as a probe: if it succeeds the circuit closes, otherwise it
+// opens for another cooldown. Rejections with a permanent 4xx status do not
+// count as failures since the endpoint answered. The breaker state is
+// exported as remote_write_circuit_breaker_state: 0 closed, 1 open and 2
+// half-open.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.breaker = &circuitBreaker{threshold: failures, cooldown: cooldown}
+	}
+}
+
+// circuitBreaker tracks consecutive send failures.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+// allowSend reports ErrCircuitOpen if the circuit breaker is open, moving it
+// to half-open once the cooldown has passed.
+func (c *Client) allowSend() error {
+	b := c.breaker
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != circuitOpen {
+		return nil
+	}
+	if time.Since(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	c.setCircuitState(circuitHalfOpen)
+	return nil
+}
+
+// recordSend updates the circuit breaker with the result of a send.
+func (c *Client) recordSend(err error) {
+	b := c.breaker
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil || permanentOnly(err) {
+		if b.state != circuitClosed {
+			c.logger.Info("endpoint recovered, closing circuit breaker")
+			c.setCircuitState(circuitClosed)
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		if b.state != circuitOpen {
+			c.logger.Warn("endpoint failing, opening circuit breaker", "failures", b.failures, "cooldown", b.cooldown)
+		}
+		b.openedAt = time.Now()
+		c.setCircuitState(circuitOpen)
+	}
+}
+
+// permanentOnly reports whether err, which may join the errors of several
+// batches, consists of permanent rejections only.
+func permanentOnly(err error) bool {
+	switch e := err.(type) {
+	case *statusError:
+		return isPermanent(e.code)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			if !permanentOnly(err) {
+				return false
+			}
+		}
+		return true
+	case interface{ Unwrap() error }:
+		return permanentOnly(e.Unwrap())
+	default:
+		return false
+	}
+}
+
+// setCircuitState changes the breaker state. It must be called with the
+// breaker locked.
+func (c *Client) setCircuitState(state int) {
+	c.breaker.state = state
+	c.metrics.circuitState.WithLabelValues(c.endpointName).Set(float64(state))
+}
+
+// logSendError logs a failed tick. Sends skipped only because circuit
+// breakers are open are logged at debug level, since the breaker already
+// logged when it opened.
+func (c *Client) logSendError(err error) {
+	if circuitOpenOnly(err) {
+		c.logger.Debug("remote write skipped", "err", err)
+		return
+	}
+	c.logger.Error("remote write failed", "err", err)
+}
+
+// circuitOpenOnly reports whether err, which may join the errors of several
+// endpoints, consists of ErrCircuitOpen only.
+func circuitOpenOnly(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !circuitOpenOnly(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, ErrCircuitOpen)
+}
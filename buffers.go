@@ -0,0 +1,40 @@
+package remotewrite
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// encodeBuffers are the scratch buffers used to marshal and compress one
+// request. They are pooled per Client so that sends at a high frequency or
+// with large payloads do not allocate them anew every time. Only the final,
+// right-sized request body is allocated per send, because it outlives the
+// send in sinks, dead letter callbacks and retries.
+type encodeBuffers struct {
+	marshal  []byte
+	compress []byte
+}
+
+func (c *Client) getBuffers() *encodeBuffers {
+	if b, ok := c.buffers.Get().(*encodeBuffers); ok {
+		return b
+	}
+	return &encodeBuffers{}
+}
+
+func (c *Client) putBuffers(b *encodeBuffers) {
+	c.buffers.Put(b)
+}
+
+// getGzipWriter returns a pooled gzip writer that writes to w.
+func (c *Client) getGzipWriter(w io.Writer) *gzip.Writer {
+	if gw, ok := c.gzipWriters.Get().(*gzip.Writer); ok {
+		gw.Reset(w)
+		return gw
+	}
+	return gzip.NewWriter(w)
+}
+
+func (c *Client) putGzipWriter(gw *gzip.Writer) {
+	c.gzipWriters.Put(gw)
+}
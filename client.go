@@ -0,0 +1,296 @@
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Client gathers metrics from a prometheus.Gatherer and pushes them to a
+// remote write endpoint.
+type Client struct {
+	url      string
+	gatherer prometheus.Gatherer
+	logger   *slog.Logger
+
+	httpClient  *http.Client
+	transport   *http.Transport
+	tls         tlsOptions
+	timeout     time.Duration
+	userAgent   string
+	headers     http.Header
+	dialNetwork string
+	http2       bool
+	sink        Sink
+	basicAuth   *basicAuth
+	bearer      *bearerToken
+	signer      func(next http.RoundTripper) (http.RoundTripper, error)
+	oauth2      *OAuth2Config
+	deadLetter  func(body []byte, statusCode int, reason string)
+	onSend      func(SendResult)
+	spool       *spool
+	breaker     *circuitBreaker
+
+	endpointName string
+	registerer   prometheus.Registerer
+	metrics      *metrics
+
+	endpointSpecs []endpointSpec
+	endpoints     []*Client
+
+	splitter BatchSplitter
+
+	adaptiveBatching bool
+	minBatchSeries   int
+	maxBatchBytes    int
+	batchMu          sync.Mutex
+	batchLimit       int // current adaptive batch size, 0 when unlimited
+
+	shards         int
+	adaptiveShards bool
+	minShards      int
+	maxShards      int
+
+	created         time.Time
+	monotonic       bool
+	clock           func() time.Time
+	timestampOffset time.Duration
+
+	// interval is the send frequency, known once RemoteWrite runs. It is
+	// guarded by the sendMu of the primary Client.
+	interval time.Duration
+
+	externalLabels []prompb.Label
+	namePrefix     string
+	nameFunc       func(base, suffix string) string
+	boundFormat    func(v float64) string
+	nameValidation NameValidation
+	exemplars      bool
+	exemplarNames  map[string]string
+	createdSeries  bool
+	seriesOrder    SeriesOrder
+	metadata       *metadataCache
+	sumType        SumType
+	filter         func(name string, labels map[string]string) bool
+	dropLabels     map[string]struct{}
+	ttl            *seriesTTL
+	stateFile      string
+	duplicates     *duplicateFilter
+	staleness      *stalenessTracker
+
+	labelCountThreshold int
+	labelCountLimit     int
+	maxLabelNameLength  int
+	maxLabelValueLength int
+	labelLimitAction    LabelLimitAction
+
+	quota *sampleQuota
+
+	tenantLabel   string
+	tenantFunc    func(labels []prompb.Label) string
+	defaultTenant string
+
+	protocol            ProtocolVersion
+	contentTypeOverride string
+	versionOverride     string
+
+	compression Compression
+	zstdLevel   zstd.EncoderLevel
+	zstdEncoder *zstd.Encoder
+	buffers     sync.Pool
+	gzipWriters sync.Pool
+
+	collectionDuration bool
+	waitFirstTick      bool
+	startJitter        bool
+	backpressure       BackpressurePolicy
+	maxQueued          int
+	maxBufferBytes     int64
+	flushTimeout       time.Duration
+	dryRun             func(wr *prompb.WriteRequest)
+
+	emptyStartup EmptyGatherPolicy
+	emptyRuntime EmptyGatherPolicy
+
+	// writeMu serializes gathers and sendMu serializes sends. A cycle
+	// takes sendMu before releasing writeMu, so sends keep gather order.
+	writeMu sync.Mutex
+	sendMu  sync.Mutex
+
+	// queue is the send queue of a running Run with BackpressureQueue. It
+	// is guarded by writeMu.
+	queue *sendQueue
+
+	// gathered records whether a gather has ever returned metric families,
+	// which separates startup emptiness from runtime emptiness.
+	gathered bool
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// EmptyGatherPolicy controls what happens when a gather returns no metric
+// families.
+type EmptyGatherPolicy int
+
+const (
+	// EmptyGatherSkip skips the send and logs at debug level.
+	EmptyGatherSkip EmptyGatherPolicy = iota
+	// EmptyGatherWarn skips the send and logs a warning.
+	EmptyGatherWarn
+	// EmptyGatherError skips the send and returns ErrEmptyGather.
+	EmptyGatherError
+)
+
+// ErrEmptyGather is returned when a gather yields no metric families and the
+// EmptyGatherError policy is in effect.
+var ErrEmptyGather = errors.New("gather returned no metric families")
+
+// WithEmptyGatherPolicy sets the behavior for empty gathers. The startup
+// policy applies until the first non-empty gather; the runtime policy applies
+// afterwards. Both default to EmptyGatherSkip.
+func WithEmptyGatherPolicy(startup, runtime EmptyGatherPolicy) Option {
+	return func(c *Client) {
+		c.emptyStartup = startup
+		c.emptyRuntime = runtime
+	}
+}
+
+// WithCollectionDuration appends a remote_write_collection_duration_seconds
+// series to every send, recording how long the gather and conversion took.
+func WithCollectionDuration() Option {
+	return func(c *Client) {
+		c.collectionDuration = true
+	}
+}
+
+// WithWaitFirstTick delays the first send by one frequency interval instead
+// of sending immediately when RemoteWrite starts.
+func WithWaitFirstTick() Option {
+	return func(c *Client) {
+		c.waitFirstTick = true
+	}
+}
+
+// WithLogger routes the client's diagnostics to logger. It defaults to
+// slog.Default(); per-send success messages are logged at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithStartJitter delays the first send by a random duration of up to one
+// frequency interval, so that a fleet of instances started together spreads
+// its sends out. Later sends keep the fixed interval from that offset.
+func WithStartJitter() Option {
+	return func(c *Client) {
+		c.startJitter = true
+	}
+}
+
+// WithFlushOnShutdown makes Run send one last batch when its context is
+// cancelled, allowing it up to timeout to complete. With BackpressureQueue,
+// the gathers still queued are sent before it, with up to timeout of their
+// own.
+func WithFlushOnShutdown(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.flushTimeout = timeout
+	}
+}
+
+// New returns a Client that writes to remoteWriteURL.
+func New(remoteWriteURL string, opts ...Option) (*Client, error) {
+	c := &Client{
+		url:      remoteWriteURL,
+		gatherer: prometheus.DefaultGatherer,
+		logger:   slog.Default(),
+		timeout:  DefaultTimeout,
+		splitter: NoSplit(),
+		shards:   1,
+		created:  time.Now(),
+		metrics:  newMetrics(),
+
+		userAgent: DefaultUserAgent,
+		zstdLevel: zstd.SpeedDefault,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if c.httpClient == nil {
+		client, err := c.newHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		c.httpClient = client
+	}
+	if err := c.wrapSigner(); err != nil {
+		return nil, err
+	}
+	c.wrapOAuth2()
+	if err := c.newCompressor(); err != nil {
+		return nil, err
+	}
+
+	if c.endpointName == "" {
+		c.endpointName = stripUserinfo(remoteWriteURL)
+	}
+	c.metrics.shards.WithLabelValues(c.endpointName).Set(float64(c.shards))
+	c.loadState()
+
+	if err := c.newEndpoints(); err != nil {
+		return nil, err
+	}
+	c.enableMetadata()
+
+	if c.registerer != nil {
+		if err := c.metrics.register(c.registerer); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// stripUserinfo returns rawURL without the credentials it may carry, so that
+// it can name the endpoint in self metrics and logs.
+func stripUserinfo(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// handleEmptyGather applies the configured EmptyGatherPolicy.
+func (c *Client) handleEmptyGather() error {
+	policy, phase := c.emptyRuntime, "runtime"
+	if !c.gathered {
+		policy, phase = c.emptyStartup, "startup"
+	}
+
+	switch policy {
+	case EmptyGatherWarn:
+		c.logger.Warn("gather returned no metric families, skipping send", "phase", phase)
+	case EmptyGatherError:
+		return fmt.Errorf("%w (%s)", ErrEmptyGather, phase)
+	default:
+		c.logger.Debug("gather returned no metric families, skipping send", "phase", phase)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package remotewrite
+
+import (
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithMonotonicTimestamps derives sample timestamps from the wall clock time
+// at which the Client was created plus the monotonic time elapsed since.
+// Timestamps then never go backwards when the wall clock is stepped, for
+// example by NTP, at the cost of drifting from it over long uptimes. The
+// default is to use the wall clock.
+func WithMonotonicTimestamps() Option {
+	return func(c *Client) {
+		c.monotonic = true
+	}
+}
+
+// WithClock stamps samples with the time returned by now instead of the wall
+// clock, for example to give all samples of a batch job the same time or to
+// make timestamps deterministic in tests. Explicit metric timestamps still
+// take precedence.
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.clock = now
+	}
+}
+
+// WithTimestampOffset adds offset to the timestamp of every sample,
+// histogram and exemplar sent, including explicit metric timestamps, to
+// compensate for a clock known to be skewed from the receiver's. A positive
+// offset shifts samples into the future and a negative one into the past,
+// for example to keep them from being rejected as too far in the future.
+// The default is no offset. It has millisecond precision.
+func WithTimestampOffset(offset time.Duration) Option {
+	return func(c *Client) {
+		c.timestampOffset = offset
+	}
+}
+
+// shiftTimestamps applies the timestamp offset to series.
+func (c *Client) shiftTimestamps(series []prompb.TimeSeries) {
+	d := c.timestampOffset.Milliseconds()
+	if d == 0 {
+		return
+	}
+
+	for i := range series {
+		ts := &series[i]
+		for j := range ts.Samples {
+			ts.Samples[j].Timestamp += d
+		}
+		for j := range ts.Histograms {
+			ts.Histograms[j].Timestamp += d
+		}
+		for j := range ts.Exemplars {
+			ts.Exemplars[j].Timestamp += d
+		}
+	}
+}
+
+// now returns the time used to stamp samples.
+func (c *Client) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	if c.monotonic {
+		return c.created.Add(time.Since(c.created))
+	}
+	return time.Now()
+}
+
+// nowMillis returns now as milliseconds since the Unix epoch.
+func (c *Client) nowMillis() int64 {
+	return c.now().UnixNano() / int64(time.Millisecond)
+}
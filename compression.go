@@ -0,0 +1,114 @@
+package remotewrite
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects the codec used to compress the request body.
+type Compression int
+
+const (
+	// CompressionSnappy uses the snappy block format required by remote
+	// write 1.0, with Content-Encoding: snappy. It is the default.
+	CompressionSnappy Compression = iota
+	// CompressionZstd uses zstd. Only use it with receivers that accept
+	// Content-Encoding: zstd.
+	CompressionZstd
+	// CompressionGzip uses gzip. Only use it with receivers that accept
+	// Content-Encoding: gzip.
+	CompressionGzip
+	// CompressionNone sends the raw protobuf without a Content-Encoding
+	// header, for packet captures and local testing. It is not part of the
+	// remote write specification.
+	CompressionNone
+	// CompressionSnappyFramed uses the snappy stream (framing) format, with
+	// Content-Encoding: x-snappy-framed. Remote write receivers expect the
+	// block format; use it only for proxies that require the stream format.
+	CompressionSnappyFramed
+)
+
+// WithCompression sets the codec used to compress the request body.
+func WithCompression(compression Compression) Option {
+	return func(c *Client) {
+		c.compression = compression
+	}
+}
+
+// WithZstdLevel sets the zstd encoder level. It only takes effect together
+// with CompressionZstd and defaults to zstd.SpeedDefault.
+func WithZstdLevel(level zstd.EncoderLevel) Option {
+	return func(c *Client) {
+		c.zstdLevel = level
+	}
+}
+
+// contentEncoding returns the Content-Encoding header value for the
+// configured codec, or "" if the header is omitted.
+func (c *Client) contentEncoding() string {
+	switch c.compression {
+	case CompressionZstd:
+		return "zstd"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionNone:
+		return ""
+	case CompressionSnappyFramed:
+		return "x-snappy-framed"
+	default:
+		return "snappy"
+	}
+}
+
+// newCompressor prepares the encoder for the configured codec.
+func (c *Client) newCompressor() error {
+	if c.compression != CompressionZstd {
+		return nil
+	}
+
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(c.zstdLevel))
+	if err != nil {
+		return fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	c.zstdEncoder = enc
+	return nil
+}
+
+// compress encodes data with the configured codec, reusing dst if it is
+// large enough. It is safe for concurrent use.
+func (c *Client) compress(dst, data []byte) ([]byte, error) {
+	switch c.compression {
+	case CompressionSnappy:
+		return snappy.Encode(dst[:cap(dst)], data), nil
+	case CompressionZstd:
+		return c.zstdEncoder.EncodeAll(data, dst[:0]), nil
+	case CompressionGzip:
+		buf := bytes.NewBuffer(dst[:0])
+		w := c.getGzipWriter(buf)
+		defer c.putGzipWriter(w)
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to gzip payload: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to gzip payload: %w", err)
+		}
+		return buf.Bytes(), nil
+	case CompressionNone:
+		return data, nil
+	case CompressionSnappyFramed:
+		buf := bytes.NewBuffer(dst[:0])
+		w := snappy.NewBufferedWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to snappy frame payload: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to snappy frame payload: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %d", c.compression)
+	}
+}
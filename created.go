@@ -0,0 +1,38 @@
+package remotewrite
+
+import (
+	"strings"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WithCreatedSeries sends a <name>_created series carrying the creation time,
+// in seconds since the epoch, of every counter, summary and histogram that
+// exposes one. Downstream it improves counter reset detection. It is off by
+// default because older receivers may not expect these series.
+func WithCreatedSeries() Option {
+	return func(c *Client) {
+		c.createdSeries = true
+	}
+}
+
+// createdTimestamp returns the creation time of m, or nil if it has none.
+func createdTimestamp(m *io_prometheus_client.Metric) *timestamppb.Timestamp {
+	switch {
+	case m.Counter != nil:
+		return m.GetCounter().GetCreatedTimestamp()
+	case m.Summary != nil:
+		return m.GetSummary().GetCreatedTimestamp()
+	case m.Histogram != nil:
+		return m.GetHistogram().GetCreatedTimestamp()
+	default:
+		return nil
+	}
+}
+
+// createdName returns the name of the _created series of the metric named
+// name. As in OpenMetrics, the _total suffix of counters is not repeated.
+func (c *Client) createdName(name string) string {
+	return c.seriesName(strings.TrimSuffix(name, "_total"), "_created")
+}
@@ -0,0 +1,20 @@
+package remotewrite
+
+import "net/http"
+
+// WithDeadLetter calls fn with every batch the endpoint rejects permanently,
+// that is with a 4xx status other than 429 Too Many Requests, once all other
+// handling such as adaptive batching has given up. body is the compressed
+// payload as it was sent, and reason describes the rejection. fn may be
+// called concurrently when more than one shard is used.
+func WithDeadLetter(fn func(body []byte, statusCode int, reason string)) Option {
+	return func(c *Client) {
+		c.deadLetter = fn
+	}
+}
+
+// isPermanent reports whether a response with the given status code will
+// fail again if the same request is retried.
+func isPermanent(code int) bool {
+	return code >= 400 && code < 500 && code != http.StatusTooManyRequests
+}
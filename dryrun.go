@@ -0,0 +1,43 @@
+package remotewrite
+
+import (
+	"strconv"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithDryRun builds every write request as usual but passes it to fn instead
+// of sending it. Quotas, tenant routing and persisted state are skipped. If
+// fn is nil, the series and sample counts are logged at info level and every
+// label set at debug level.
+func WithDryRun(fn func(wr *prompb.WriteRequest)) Option {
+	return func(c *Client) {
+		if fn == nil {
+			fn = c.logWriteRequest
+		}
+		c.dryRun = fn
+	}
+}
+
+// logWriteRequest logs a summary of wr.
+func (c *Client) logWriteRequest(wr *prompb.WriteRequest) {
+	c.logger.Info("dry run, not sending write request",
+		"series", len(wr.Timeseries), "samples", sampleCount(wr.Timeseries))
+	for _, ts := range wr.Timeseries {
+		c.logger.Debug("dry run series", "labels", labelsString(ts.Labels))
+	}
+}
+
+// labelsString formats labels as {name="value", ...}.
+func labelsString(labels []prompb.Label) string {
+	b := []byte{'{'}
+	for i, l := range labels {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = append(b, l.Name...)
+		b = append(b, '=')
+		b = strconv.AppendQuote(b, l.Value)
+	}
+	return string(append(b, '}'))
+}
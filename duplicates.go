@@ -0,0 +1,101 @@
+package remotewrite
+
+import (
+	"math"
+	"sync"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithSkipDuplicateSamples skips a series when its sample has the same
+// millisecond timestamp and value as the previous sample sent for it. This
+// happens when explicit timestamps, such as federated ones, do not advance
+// between sends, and avoids duplicate sample rejections from receivers.
+// Samples of a failed batch are not considered sent, so they go out again
+// with the next gather unless WithSpool keeps the batch.
+func WithSkipDuplicateSamples() Option {
+	return func(c *Client) {
+		c.duplicates = newDuplicateFilter()
+	}
+}
+
+// duplicateFilter remembers the last sample sent for each series. It is
+// shared by a Client and its additional endpoints, which forget the samples
+// of sends that fail.
+type duplicateFilter struct {
+	mu         sync.Mutex
+	last       map[string]lastSample
+	generation uint64
+}
+
+type lastSample struct {
+	timestamp  int64
+	value      uint64 // float64 bits, so NaN compares equal to itself
+	generation uint64
+}
+
+func newDuplicateFilter() *duplicateFilter {
+	return &duplicateFilter{last: map[string]lastSample{}}
+}
+
+// begin starts tracking a new gather.
+func (f *duplicateFilter) begin() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.generation++
+}
+
+// fresh reports whether s differs from the last sample of the series
+// identified by key, and records it.
+func (f *duplicateFilter) fresh(key string, s prompb.Sample) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bits := math.Float64bits(s.Value)
+	prev, ok := f.last[key]
+	f.last[key] = lastSample{timestamp: s.Timestamp, value: bits, generation: f.generation}
+
+	return !ok || prev.timestamp != s.Timestamp || prev.value != bits
+}
+
+// sweep forgets series that were not part of the current gather.
+func (f *duplicateFilter) sweep() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, s := range f.last {
+		if s.generation != f.generation {
+			delete(f.last, key)
+		}
+	}
+}
+
+// forgetUnsent drops what the duplicate filter and staleness tracker recorded
+// for series, a batch that was neither delivered nor spooled, so that the
+// next gather sends it again.
+func (c *Client) forgetUnsent(series []prompb.TimeSeries) {
+	c.duplicates.forget(series)
+	c.staleness.forget(series)
+}
+
+// forget drops the recorded samples of series. A series whose record was
+// replaced by a later gather is left alone. It does nothing for a nil filter.
+func (f *duplicateFilter) forget(series []prompb.TimeSeries) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, ts := range series {
+		if len(ts.Samples) == 0 {
+			continue
+		}
+		key := seriesKey(ts.Labels)
+		s := ts.Samples[len(ts.Samples)-1]
+		if prev, ok := f.last[key]; ok && prev.timestamp == s.Timestamp && prev.value == math.Float64bits(s.Value) {
+			delete(f.last, key)
+		}
+	}
+}
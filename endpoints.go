@@ -0,0 +1,104 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// endpointSpec is an additional endpoint configured with WithEndpoint.
+type endpointSpec struct {
+	url  string
+	opts []Option
+}
+
+// WithEndpoint also sends every gathered batch to the remote write endpoint
+// at url, for example a backup backend. opts configure how that endpoint is
+// written to, such as its authentication, transport, compression, batching or
+// endpoint name; options that affect gathering and conversion only take
+// effect on the primary Client. Sends to each endpoint are independent, so a
+// failing endpoint does not keep the others from receiving the batch, and
+// their errors are joined.
+func WithEndpoint(url string, opts ...Option) Option {
+	return func(c *Client) {
+		c.endpointSpecs = append(c.endpointSpecs, endpointSpec{url: url, opts: opts})
+	}
+}
+
+// newEndpoints builds a Client for every additional endpoint. They share the
+// primary Client's self metrics, which are labelled by endpoint, and its
+// duplicate sample filter and staleness tracker.
+func (c *Client) newEndpoints() error {
+	for _, spec := range c.endpointSpecs {
+		e, err := New(spec.url, spec.opts...)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint %s: %w", stripUserinfo(spec.url), err)
+		}
+		e.metrics = c.metrics
+		e.metrics.shards.WithLabelValues(e.endpointName).Set(float64(e.shards))
+		e.duplicates = c.duplicates
+		e.staleness = c.staleness
+		c.endpoints = append(c.endpoints, e)
+	}
+	return nil
+}
+
+// sendAll sends series to the primary endpoint and every additional one
+// concurrently. The series are gathered and converted once and only read by
+// the sends.
+func (c *Client) sendAll(ctx context.Context, series []prompb.TimeSeries) error {
+	if len(c.endpoints) == 0 {
+		return c.send(ctx, series)
+	}
+
+	clients := append([]*Client{c}, c.endpoints...)
+	errs := make([]error, len(clients))
+
+	var wg sync.WaitGroup
+	for i, e := range clients {
+		wg.Add(1)
+		go func(i int, e *Client) {
+			defer wg.Done()
+			if err := e.send(ctx, series); err != nil {
+				errs[i] = fmt.Errorf("endpoint %s: %w", e.endpointName, err)
+			}
+		}(i, e)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// send splits series into batches and sends them to this Client's endpoint,
+// after any spooled payloads, unless the circuit breaker is open.
+func (c *Client) send(ctx context.Context, series []prompb.TimeSeries) error {
+	var jobs []batchJob
+	for _, part := range c.partitionByTenant(series) {
+		for _, batch := range c.splitter.Split(part.series) {
+			jobs = append(jobs, batchJob{series: batch, tenant: part.tenant})
+		}
+	}
+
+	if err := c.allowSend(); err != nil {
+		c.spoolJobs(jobs)
+		c.forgetUnsent(series)
+		return err
+	}
+
+	if err := c.drainSpool(ctx); err != nil {
+		c.logger.Warn("spool not drained, sending fresh data", "err", err)
+	}
+
+	start := time.Now()
+	err := c.sendBatches(ctx, jobs)
+	c.reshard(time.Since(start))
+	if ctx.Err() == nil {
+		c.recordSend(err)
+	}
+
+	return err
+}
@@ -0,0 +1,48 @@
+package remotewrite
+
+import (
+	"fmt"
+	"os"
+)
+
+// Environment variables read by NewFromEnv.
+const (
+	envURL         = "REMOTE_WRITE_URL"
+	envUsername    = "REMOTE_WRITE_USERNAME"
+	envPassword    = "REMOTE_WRITE_PASSWORD"
+	envBearerToken = "REMOTE_WRITE_BEARER_TOKEN"
+	envTenant      = "REMOTE_WRITE_TENANT"
+)
+
+// NewFromEnv creates a Client configured from the environment:
+//
+//   - REMOTE_WRITE_URL is the remote write endpoint. It is required.
+//   - REMOTE_WRITE_USERNAME and REMOTE_WRITE_PASSWORD enable basic auth.
+//   - REMOTE_WRITE_BEARER_TOKEN enables bearer token auth.
+//   - REMOTE_WRITE_TENANT sets the X-Scope-OrgID header.
+//
+// Empty variables are treated as unset. opts are applied after the options
+// derived from the environment.
+func NewFromEnv(opts ...Option) (*Client, error) {
+	url := os.Getenv(envURL)
+	if url == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", envURL)
+	}
+
+	var envOpts []Option
+	username, password := os.Getenv(envUsername), os.Getenv(envPassword)
+	switch {
+	case username != "":
+		envOpts = append(envOpts, WithBasicAuth(username, password))
+	case password != "":
+		return nil, fmt.Errorf("environment variable %s is set without %s", envPassword, envUsername)
+	}
+	if token := os.Getenv(envBearerToken); token != "" {
+		envOpts = append(envOpts, WithBearerToken(token))
+	}
+	if tenant := os.Getenv(envTenant); tenant != "" {
+		envOpts = append(envOpts, WithHeaders(map[string]string{tenantHeader: tenant}))
+	}
+
+	return New(url, append(envOpts, opts...)...)
+}
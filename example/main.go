@@ -119,7 +119,11 @@ func main() {
 	api := newSampleAPI(prometheus.DefaultRegisterer)
 	api.register(http.DefaultServeMux)
 
-	go prw.RemoteWrite(*remoteWriteURL, *frequency)
+	writer, err := prw.New(*remoteWriteURL, prw.WithTimeout(10*time.Second))
+	if err != nil {
+		log.Fatal(err)
+	}
+	go writer.RemoteWrite(*frequency)
 
 	log.Fatal(http.ListenAndServe(*listenAddr, nil))
 }
@@ -0,0 +1,56 @@
+package remotewrite
+
+import (
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithExemplars sends the exemplars attached to counters and histogram
+// buckets along with their series. It is off by default because not every
+// receiver accepts exemplars.
+func WithExemplars() Option {
+	return func(c *Client) {
+		c.exemplars = true
+	}
+}
+
+// WithExemplarLabelNames renames exemplar label names during conversion,
+// mapping each old name to a new one, for receivers that expect another
+// convention such as trace_id instead of traceID. Labels not in names are
+// sent unchanged.
+func WithExemplarLabelNames(names map[string]string) Option {
+	return func(c *Client) {
+		c.exemplarNames = names
+	}
+}
+
+// convertExemplars converts the non-nil exemplars in es. Exemplars without a
+// timestamp are stamped with fallback.
+func (c *Client) convertExemplars(es []*io_prometheus_client.Exemplar, fallback int64) []prompb.Exemplar {
+	if !c.exemplars {
+		return nil
+	}
+
+	var out []prompb.Exemplar
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		labels := make([]prompb.Label, 0, len(e.GetLabel()))
+		for _, lp := range e.GetLabel() {
+			name := lp.GetName()
+			if to, ok := c.exemplarNames[name]; ok {
+				name = to
+			}
+			labels = append(labels, prompb.Label{Name: name, Value: lp.GetValue()})
+		}
+		sortLabels(labels)
+
+		ts := fallback
+		if e.Timestamp != nil {
+			ts = e.GetTimestamp().AsTime().UnixMilli()
+		}
+		out = append(out, prompb.Exemplar{Labels: labels, Value: e.GetValue(), Timestamp: ts})
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package remotewrite
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// FederateGatherer is a prometheus.Gatherer that scrapes the /federate
+// endpoint of another Prometheus server. Federated samples carry their
+// original timestamps, which are preserved when they are written.
+type FederateGatherer struct {
+	// URL of the federate endpoint, e.g. http://prometheus:9090/federate.
+	URL string
+	// Matchers are the match[] series selectors to federate.
+	Matchers []string
+	// Client is used for the scrape. If nil, a client with a
+	// DefaultTimeout timeout is used, so a hung server cannot block the
+	// gather forever.
+	Client *http.Client
+}
+
+// defaultFederateClient scrapes federate endpoints unless
+// FederateGatherer.Client is set.
+var defaultFederateClient = &http.Client{Timeout: DefaultTimeout}
+
+// NewFederateGatherer returns a FederateGatherer for the endpoint at
+// federateURL selecting the series matched by matchers.
+func NewFederateGatherer(federateURL string, matchers ...string) *FederateGatherer {
+	return &FederateGatherer{URL: federateURL, Matchers: matchers}
+}
+
+// Gather implements prometheus.Gatherer.
+func (g *FederateGatherer) Gather() ([]*io_prometheus_client.MetricFamily, error) {
+	u, err := url.Parse(g.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid federate URL: %w", err)
+	}
+	q := u.Query()
+	for _, m := range g.Matchers {
+		q.Add("match[]", m)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create federate request: %w", err)
+	}
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+
+	client := g.Client
+	if client == nil {
+		client = defaultFederateClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape federate endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected federate response status: %s", resp.Status)
+	}
+
+	var parser expfmt.TextParser
+	byName, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse federate response: %w", err)
+	}
+
+	mfs := make([]*io_prometheus_client.MetricFamily, 0, len(byName))
+	for _, mf := range byName {
+		mfs = append(mfs, mf)
+	}
+	sort.Slice(mfs, func(i, j int) bool {
+		return mfs[i].GetName() < mfs[j].GetName()
+	})
+
+	return mfs, nil
+}
@@ -0,0 +1,27 @@
+package remotewrite
+
+import (
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// WithFilter sends only the metrics for which keep returns true. keep is
+// called with the metric family name and the metric's own labels, before
+// label dropping and external labels are applied. Rejected metrics never
+// become time series.
+func WithFilter(keep func(name string, labels map[string]string) bool) Option {
+	return func(c *Client) {
+		c.filter = keep
+	}
+}
+
+// keepMetric reports whether m of the family named name passes the filter.
+func (c *Client) keepMetric(name string, m *io_prometheus_client.Metric) bool {
+	if c.filter == nil {
+		return true
+	}
+	labels := make(map[string]string, len(m.Label))
+	for _, lp := range m.Label {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+	return c.filter(name, labels)
+}
@@ -0,0 +1,90 @@
+package remotewrite
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// WithGatherer sets the gatherer whose metrics are sent, such as a private
+// *prometheus.Registry. It defaults to prometheus.DefaultGatherer.
+func WithGatherer(g prometheus.Gatherer) Option {
+	return func(c *Client) {
+		c.gatherer = g
+	}
+}
+
+// NamedGatherer is a prometheus.Gatherer identified by a name.
+type NamedGatherer struct {
+	Name     string
+	Gatherer prometheus.Gatherer
+}
+
+// WithNamedGatherers gathers from every gatherer in gatherers and merges the
+// results. If sourceLabel is not empty, each series is tagged with
+// sourceLabel set to the Name of the gatherer it came from; a label of the
+// same name already present on the series is left untouched.
+func WithNamedGatherers(sourceLabel string, gatherers ...NamedGatherer) Option {
+	return func(c *Client) {
+		c.gatherer = namedGatherers{label: sourceLabel, gatherers: gatherers}
+	}
+}
+
+type namedGatherers struct {
+	label     string
+	gatherers []NamedGatherer
+}
+
+// Gather implements prometheus.Gatherer. Like prometheus.Gatherers, it
+// returns whatever could be gathered alongside a prometheus.MultiError.
+func (g namedGatherers) Gather() ([]*io_prometheus_client.MetricFamily, error) {
+	var (
+		mfs  []*io_prometheus_client.MetricFamily
+		errs prometheus.MultiError
+	)
+
+	for _, ng := range g.gatherers {
+		gathered, err := ng.Gatherer.Gather()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gatherer %q: %w", ng.Name, err))
+		}
+
+		for _, mf := range gathered {
+			if g.label != "" {
+				mf = withSourceLabel(mf, g.label, ng.Name)
+			}
+			mfs = append(mfs, mf)
+		}
+	}
+
+	return mfs, errs.MaybeUnwrap()
+}
+
+// withSourceLabel returns a copy of mf with the label name=value added to
+// every metric that does not already carry name.
+func withSourceLabel(mf *io_prometheus_client.MetricFamily, name, value string) *io_prometheus_client.MetricFamily {
+	mf = proto.Clone(mf).(*io_prometheus_client.MetricFamily)
+
+	for _, m := range mf.Metric {
+		if hasLabel(m, name) {
+			continue
+		}
+		m.Label = append(m.Label, &io_prometheus_client.LabelPair{
+			Name:  proto.String(name),
+			Value: proto.String(value),
+		})
+	}
+
+	return mf
+}
+
+func hasLabel(m *io_prometheus_client.Metric, name string) bool {
+	for _, lp := range m.Label {
+		if lp.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
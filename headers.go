@@ -0,0 +1,36 @@
+package remotewrite
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// protectedHeaders are set by the Client and cannot be overridden with
+// WithHeaders.
+var protectedHeaders = []string{"Content-Encoding", "Content-Type", versionHeader}
+
+// WithHeaders adds static headers, such as X-Scope-OrgID for a fixed tenant,
+// to every request. A tenant chosen by WithTenantLabel takes precedence over
+// a static X-Scope-OrgID. Content-Encoding, Content-Type and
+// X-Prometheus-Remote-Write-Version cannot be set this way.
+func WithHeaders(headers map[string]string) Option {
+	return func(c *Client) {
+		if c.headers == nil {
+			c.headers = make(http.Header, len(headers))
+		}
+		for name, value := range headers {
+			c.headers.Set(name, value)
+		}
+	}
+}
+
+// validateHeaders rejects static headers that would clobber the protocol
+// headers.
+func (c *Client) validateHeaders() error {
+	for _, name := range protectedHeaders {
+		if _, ok := c.headers[http.CanonicalHeaderKey(name)]; ok {
+			return fmt.Errorf("header %s cannot be overridden", name)
+		}
+	}
+	return nil
+}
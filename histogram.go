@@ -0,0 +1,100 @@
+package remotewrite
+
+import (
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+)
+
+// isNativeHistogram reports whether h carries native (exponential) buckets.
+// client_golang exposes an empty span for a native histogram without
+// observations so that it can still be recognized.
+func isNativeHistogram(h *io_prometheus_client.Histogram) bool {
+	return len(h.GetPositiveSpan()) > 0 ||
+		len(h.GetNegativeSpan()) > 0 ||
+		h.GetZeroThreshold() > 0 ||
+		h.GetZeroCount() > 0 ||
+		h.GetZeroCountFloat() > 0
+}
+
+// nativeHistogram converts the native buckets of h to a remote write
+// histogram. Float histograms keep absolute counts; integer histograms keep
+// the delta encoding of the exposition.
+func nativeHistogram(h *io_prometheus_client.Histogram, timestamp int64, gauge bool) prompb.Histogram {
+	out := prompb.Histogram{
+		Sum:           h.GetSampleSum(),
+		Schema:        h.GetSchema(),
+		ZeroThreshold: h.GetZeroThreshold(),
+		NegativeSpans: bucketSpans(h.GetNegativeSpan()),
+		PositiveSpans: bucketSpans(h.GetPositiveSpan()),
+		Timestamp:     timestamp,
+	}
+	if h.SampleCountFloat != nil || h.ZeroCountFloat != nil {
+		out.Count = &prompb.Histogram_CountFloat{CountFloat: h.GetSampleCountFloat()}
+		out.ZeroCount = &prompb.Histogram_ZeroCountFloat{ZeroCountFloat: h.GetZeroCountFloat()}
+		out.NegativeCounts = h.GetNegativeCount()
+		out.PositiveCounts = h.GetPositiveCount()
+	} else {
+		out.Count = &prompb.Histogram_CountInt{CountInt: h.GetSampleCount()}
+		out.ZeroCount = &prompb.Histogram_ZeroCountInt{ZeroCountInt: h.GetZeroCount()}
+		out.NegativeDeltas = h.GetNegativeDelta()
+		out.PositiveDeltas = h.GetPositiveDelta()
+	}
+	if gauge {
+		out.ResetHint = prompb.Histogram_GAUGE
+	}
+	return out
+}
+
+func bucketSpans(spans []*io_prometheus_client.BucketSpan) []prompb.BucketSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]prompb.BucketSpan, 0, len(spans))
+	for _, s := range spans {
+		out = append(out, prompb.BucketSpan{Offset: s.GetOffset(), Length: s.GetLength()})
+	}
+	return out
+}
+
+// toWriteV2Histogram converts a remote write 1.0 histogram to its 2.0
+// equivalent.
+func toWriteV2Histogram(h prompb.Histogram) writev2.Histogram {
+	out := writev2.Histogram{
+		Sum:            h.Sum,
+		Schema:         h.Schema,
+		ZeroThreshold:  h.ZeroThreshold,
+		NegativeSpans:  toWriteV2Spans(h.NegativeSpans),
+		NegativeDeltas: h.NegativeDeltas,
+		NegativeCounts: h.NegativeCounts,
+		PositiveSpans:  toWriteV2Spans(h.PositiveSpans),
+		PositiveDeltas: h.PositiveDeltas,
+		PositiveCounts: h.PositiveCounts,
+		ResetHint:      writev2.Histogram_ResetHint(h.ResetHint),
+		Timestamp:      h.Timestamp,
+	}
+	switch c := h.Count.(type) {
+	case *prompb.Histogram_CountInt:
+		out.Count = &writev2.Histogram_CountInt{CountInt: c.CountInt}
+	case *prompb.Histogram_CountFloat:
+		out.Count = &writev2.Histogram_CountFloat{CountFloat: c.CountFloat}
+	}
+	switch z := h.ZeroCount.(type) {
+	case *prompb.Histogram_ZeroCountInt:
+		out.ZeroCount = &writev2.Histogram_ZeroCountInt{ZeroCountInt: z.ZeroCountInt}
+	case *prompb.Histogram_ZeroCountFloat:
+		out.ZeroCount = &writev2.Histogram_ZeroCountFloat{ZeroCountFloat: z.ZeroCountFloat}
+	}
+	return out
+}
+
+func toWriteV2Spans(spans []prompb.BucketSpan) []writev2.BucketSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]writev2.BucketSpan, 0, len(spans))
+	for _, s := range spans {
+		out = append(out, writev2.BucketSpan{Offset: s.Offset, Length: s.Length})
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+// Package kafkasink provides a remotewrite.Sink that produces remote write
+// payloads to a Kafka topic.
+package kafkasink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+
+	prw "github.com/pree-dew/prometheus-remote-write"
+)
+
+// tenantHeader is the payload header carrying the tenant, if any.
+const tenantHeader = "X-Scope-OrgID"
+
+// Sink produces each payload as a single Kafka message. The message value is
+// the compressed protobuf WriteRequest, the payload headers become message
+// headers, and the tenant, when set, is used as the message key.
+type Sink struct {
+	w *kafka.Writer
+}
+
+// New returns a Sink producing to topic on brokers.
+func New(brokers []string, topic string) *Sink {
+	return NewWithWriter(&kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+	})
+}
+
+// NewWithWriter returns a Sink producing with w, which must have its Topic
+// set.
+func NewWithWriter(w *kafka.Writer) *Sink {
+	return &Sink{w: w}
+}
+
+// Send implements remotewrite.Sink.
+func (s *Sink) Send(ctx context.Context, p prw.Payload) error {
+	msg := kafka.Message{Value: p.Body}
+	for name := range p.Header {
+		msg.Headers = append(msg.Headers, kafka.Header{
+			Key:   name,
+			Value: []byte(p.Header.Get(name)),
+		})
+	}
+	if tenant := p.Header.Get(tenantHeader); tenant != "" {
+		msg.Key = []byte(tenant)
+	}
+
+	if err := s.w.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to produce to kafka: %w", err)
+	}
+	return nil
+}
+
+// Close flushes pending messages and closes the underlying writer.
+func (s *Sink) Close() error {
+	return s.w.Close()
+}
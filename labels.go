@@ -0,0 +1,286 @@
+package remotewrite
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithDropLabels removes the named labels from every series before it is
+// sent. Series that become identical once the labels are removed are merged,
+// keeping the last one.
+func WithDropLabels(names ...string) Option {
+	return func(c *Client) {
+		if c.dropLabels == nil {
+			c.dropLabels = make(map[string]struct{}, len(names))
+		}
+		for _, name := range names {
+			c.dropLabels[name] = struct{}{}
+		}
+	}
+}
+
+// WithExternalLabels adds labels to every series sent, such as cluster or
+// instance labels identifying the source. A label the series already carries
+// takes precedence over an external label of the same name.
+func WithExternalLabels(labels map[string]string) Option {
+	return func(c *Client) {
+		c.externalLabels = c.externalLabels[:0]
+		for name, value := range labels {
+			c.externalLabels = append(c.externalLabels, prompb.Label{Name: name, Value: value})
+		}
+		sort.Slice(c.externalLabels, func(i, j int) bool {
+			return c.externalLabels[i].Name < c.externalLabels[j].Name
+		})
+	}
+}
+
+// WithNamePrefix prepends prefix, such as "myservice_", to the name of every
+// metric sent. Summary and histogram suffixes are added after the prefixed
+// name.
+func WithNamePrefix(prefix string) Option {
+	return func(c *Client) {
+		c.namePrefix = prefix
+	}
+}
+
+// WithNameFunc sets how the name of each series is built from the metric
+// name, after WithNamePrefix is applied, and a suffix: "" for the metric
+// itself, or "_bucket", "_sum", "_count" or "_created". For example, it can
+// keep suffixes ahead of the colon-separated parts of recording rule style
+// names. The default concatenates base and suffix. For counters, the _total
+// suffix is trimmed from base before it is passed with "_created".
+func WithNameFunc(fn func(base, suffix string) string) Option {
+	return func(c *Client) {
+		c.nameFunc = fn
+	}
+}
+
+// WithBoundFormat sets how bucket upper bounds and quantiles are formatted
+// in le and quantile label values, for example with a fixed precision to
+// match buckets from another source. The default matches Prometheus:
+// strconv.FormatFloat(v, 'g', -1, 64), with +Inf for the infinite bound.
+func WithBoundFormat(format func(v float64) string) Option {
+	return func(c *Client) {
+		c.boundFormat = format
+	}
+}
+
+// formatBound formats an le or quantile label value.
+func (c *Client) formatBound(v float64) string {
+	if c.boundFormat == nil {
+		return formatFloat(v)
+	}
+	return c.boundFormat(v)
+}
+
+// seriesName returns the name of the series of the metric named base with
+// the given suffix.
+func (c *Client) seriesName(base, suffix string) string {
+	if c.nameFunc == nil {
+		return base + suffix
+	}
+	return c.nameFunc(base, suffix)
+}
+
+// withExternalLabels appends the external labels not already present in
+// labels.
+func (c *Client) withExternalLabels(labels []prompb.Label) []prompb.Label {
+	for _, ext := range c.externalLabels {
+		if !containsLabel(labels, ext.Name) {
+			labels = append(labels, ext)
+		}
+	}
+	return labels
+}
+
+func containsLabel(labels []prompb.Label, name string) bool {
+	for _, l := range labels {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithLabelCountLimits guards against series with too many labels, counting
+// __name__. Series with more than threshold labels are counted in
+// remote_write_series_label_count_exceeded_total. If limit is positive,
+// series with more than limit labels are dropped. Both are disabled when zero.
+func WithLabelCountLimits(threshold, limit int) Option {
+	return func(c *Client) {
+		c.labelCountThreshold = threshold
+		c.labelCountLimit = limit
+	}
+}
+
+// checkLabelCounts records label count statistics for series and removes
+// those over the hard limit.
+func (c *Client) checkLabelCounts(series []prompb.TimeSeries) []prompb.TimeSeries {
+	if c.labelCountThreshold <= 0 && c.labelCountLimit <= 0 {
+		return series
+	}
+
+	maxCount := 0
+	out := series[:0]
+	for _, ts := range series {
+		n := len(ts.Labels)
+		if n > maxCount {
+			maxCount = n
+		}
+		if c.labelCountThreshold > 0 && n > c.labelCountThreshold {
+			c.metrics.labelCountExceeded.Inc()
+		}
+		if c.labelCountLimit > 0 && n > c.labelCountLimit {
+			c.logger.Warn("dropping series with too many labels", "name", labelValue(ts.Labels, "__name__"), "labels", n)
+			c.metrics.seriesDropped.WithLabelValues("label_count").Inc()
+			continue
+		}
+		out = append(out, ts)
+	}
+	c.metrics.maxLabelCount.Set(float64(maxCount))
+
+	return out
+}
+
+// Mimir's default label length limits, suitable for WithLabelLengthLimits.
+const (
+	MimirMaxLabelNameLength  = 1024
+	MimirMaxLabelValueLength = 2048
+)
+
+// LabelLimitAction is what happens to a series with a label over the length
+// limits.
+type LabelLimitAction int
+
+const (
+	// LabelLimitDrop drops the series.
+	LabelLimitDrop LabelLimitAction = iota
+	// LabelLimitTruncate shortens the offending label names and values to
+	// the limit.
+	LabelLimitTruncate
+)
+
+// WithLabelLengthLimits guards against label names longer than maxName bytes
+// and label values longer than maxValue bytes, which receivers such as Mimir
+// reject together with the rest of the request. Offending series are logged
+// and dropped or truncated according to action. A limit of zero disables it.
+func WithLabelLengthLimits(maxName, maxValue int, action LabelLimitAction) Option {
+	return func(c *Client) {
+		c.maxLabelNameLength = maxName
+		c.maxLabelValueLength = maxValue
+		c.labelLimitAction = action
+	}
+}
+
+// checkLabelLengths drops or truncates series with overlong label names or
+// values.
+func (c *Client) checkLabelLengths(series []prompb.TimeSeries) []prompb.TimeSeries {
+	if c.maxLabelNameLength <= 0 && c.maxLabelValueLength <= 0 {
+		return series
+	}
+
+	out := series[:0]
+	for _, ts := range series {
+		name, found := c.overlongLabel(ts.Labels)
+		if !found {
+			out = append(out, ts)
+			continue
+		}
+
+		metric := labelValue(ts.Labels, "__name__")
+		if c.labelLimitAction == LabelLimitDrop {
+			c.logger.Warn("dropping series with overlong label", "name", metric, "label", name)
+			c.metrics.seriesDropped.WithLabelValues("label_length").Inc()
+			continue
+		}
+
+		c.logger.Warn("truncating overlong label", "name", metric, "label", name)
+		for i := range ts.Labels {
+			ts.Labels[i].Name = truncate(ts.Labels[i].Name, c.maxLabelNameLength)
+			ts.Labels[i].Value = truncate(ts.Labels[i].Value, c.maxLabelValueLength)
+		}
+		sortLabels(ts.Labels)
+		out = append(out, ts)
+	}
+	return out
+}
+
+// overlongLabel returns the name of the first label over the length limits,
+// if any.
+func (c *Client) overlongLabel(labels []prompb.Label) (string, bool) {
+	for _, l := range labels {
+		if (c.maxLabelNameLength > 0 && len(l.Name) > c.maxLabelNameLength) ||
+			(c.maxLabelValueLength > 0 && len(l.Value) > c.maxLabelValueLength) {
+			return l.Name, true
+		}
+	}
+	return "", false
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+// It returns s unchanged if n is not positive.
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// keepLabel reports whether the label name survives WithDropLabels.
+func (c *Client) keepLabel(name string) bool {
+	_, drop := c.dropLabels[name]
+	return !drop
+}
+
+// sortLabels sorts labels by name, as remote write requires.
+func sortLabels(labels []prompb.Label) {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+}
+
+// seriesKey returns a string that uniquely identifies a label set regardless
+// of label order.
+func seriesKey(labels []prompb.Label) string {
+	sorted := make([]prompb.Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var b strings.Builder
+	for _, l := range sorted {
+		b.WriteString(l.Name)
+		b.WriteByte(0xff)
+		b.WriteString(l.Value)
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+// dedupSeries merges series with identical label sets, keeping the last
+// occurrence in the position of the first. Every merged duplicate is logged
+// and counted by metric name.
+func (c *Client) dedupSeries(series []prompb.TimeSeries) []prompb.TimeSeries {
+	index := make(map[string]int, len(series))
+	out := series[:0]
+	for _, ts := range series {
+		key := seriesKey(ts.Labels)
+		if i, ok := index[key]; ok {
+			name := labelValue(ts.Labels, "__name__")
+			c.logger.Warn("dropping duplicate series", "name", name)
+			c.metrics.duplicateSeries.WithLabelValues(name).Inc()
+			out[i] = ts
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, ts)
+	}
+	return out
+}
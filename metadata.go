@@ -0,0 +1,135 @@
+package remotewrite
+
+import (
+	"sync/atomic"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+)
+
+// SumType is the remote write 2.0 metadata type of the _sum series of
+// summaries and classic histograms. Gauge histograms keep their type.
+type SumType int
+
+const (
+	// SumTypeCounter sends _sum series as counters. It is the default.
+	SumTypeCounter SumType = iota
+	// SumTypeGauge sends _sum series as gauges, for sums that can decrease
+	// because of negative observations, so that they are not treated as
+	// counter resets.
+	SumTypeGauge
+)
+
+// WithSumType sets the metadata type of _sum series. It only affects remote
+// write 2.0 requests; the other series of a summary or histogram keep the
+// family's type.
+func WithSumType(t SumType) Option {
+	return func(c *Client) {
+		c.sumType = t
+	}
+}
+
+// seriesMetadata is the type, help text and unit of the metric family a
+// series belongs to.
+type seriesMetadata struct {
+	typ  writev2.Metadata_MetricType
+	help string
+	unit string
+}
+
+// metadataCache maps series names to the metadata of their metric family as
+// of the last gather, for remote write 2.0 requests. It is shared by a
+// Client and its additional endpoints.
+type metadataCache struct {
+	current atomic.Pointer[map[string]seriesMetadata]
+	next    map[string]seriesMetadata
+}
+
+// enableMetadata starts recording metadata if the Client or any additional
+// endpoint sends remote write 2.0 requests, which carry it.
+func (c *Client) enableMetadata() {
+	v2 := c.protocol == ProtocolV2
+	for _, e := range c.endpoints {
+		v2 = v2 || e.protocol == ProtocolV2
+	}
+	if !v2 {
+		return
+	}
+
+	c.metadata = &metadataCache{}
+	for _, e := range c.endpoints {
+		e.metadata = c.metadata
+	}
+}
+
+// begin starts recording the metadata of a gather.
+func (m *metadataCache) begin() {
+	m.next = make(map[string]seriesMetadata, len(m.load()))
+}
+
+// addMetadata records the metadata of mf for every series converted from it.
+func (c *Client) addMetadata(series []prompb.TimeSeries, mf *io_prometheus_client.MetricFamily) {
+	md := seriesMetadata{
+		typ:  metadataType(mf.GetType()),
+		help: mf.GetHelp(),
+		unit: mf.GetUnit(),
+	}
+
+	var sumName string
+	switch mf.GetType() {
+	case io_prometheus_client.MetricType_SUMMARY, io_prometheus_client.MetricType_HISTOGRAM:
+		sumName = c.seriesName(c.namePrefix+mf.GetName(), "_sum")
+	}
+	sum := md
+	sum.typ = writev2.Metadata_METRIC_TYPE_COUNTER
+	if c.sumType == SumTypeGauge {
+		sum.typ = writev2.Metadata_METRIC_TYPE_GAUGE
+	}
+
+	for _, ts := range series {
+		name := labelValue(ts.Labels, "__name__")
+		if name == sumName && len(ts.Histograms) == 0 {
+			c.metadata.next[name] = sum
+			continue
+		}
+		c.metadata.next[name] = md
+	}
+}
+
+// commit replaces the metadata with that recorded since begin.
+func (m *metadataCache) commit() {
+	next := m.next
+	m.current.Store(&next)
+	m.next = nil
+}
+
+// load returns the metadata of the last gather. It may be called on a nil
+// cache.
+func (m *metadataCache) load() map[string]seriesMetadata {
+	if m == nil {
+		return nil
+	}
+	if p := m.current.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// metadataType returns the remote write 2.0 type of a metric family type.
+func metadataType(t io_prometheus_client.MetricType) writev2.Metadata_MetricType {
+	switch t {
+	case io_prometheus_client.MetricType_COUNTER:
+		return writev2.Metadata_METRIC_TYPE_COUNTER
+	case io_prometheus_client.MetricType_GAUGE:
+		return writev2.Metadata_METRIC_TYPE_GAUGE
+	case io_prometheus_client.MetricType_SUMMARY:
+		return writev2.Metadata_METRIC_TYPE_SUMMARY
+	case io_prometheus_client.MetricType_HISTOGRAM:
+		return writev2.Metadata_METRIC_TYPE_HISTOGRAM
+	case io_prometheus_client.MetricType_GAUGE_HISTOGRAM:
+		return writev2.Metadata_METRIC_TYPE_GAUGEHISTOGRAM
+	default:
+		return writev2.Metadata_METRIC_TYPE_UNSPECIFIED
+	}
+}
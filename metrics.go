@@ -0,0 +1,164 @@
+package remotewrite
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics are the writer's own metrics.
+type metrics struct {
+	bytesSent          *prometheus.CounterVec
+	samplesSent        *prometheus.CounterVec
+	sendsFailed        *prometheus.CounterVec
+	sendDuration       *prometheus.HistogramVec
+	payloadBytes       *prometheus.HistogramVec
+	lastSuccess        *prometheus.GaugeVec
+	seriesDropped      *prometheus.CounterVec
+	familiesSkipped    prometheus.Counter
+	duplicateSeries    *prometheus.CounterVec
+	maxLabelCount      prometheus.Gauge
+	labelCountExceeded prometheus.Counter
+	quotaRemaining     prometheus.Gauge
+	shards             *prometheus.GaugeVec
+	desiredShards      *prometheus.GaugeVec
+	missedTicks        prometheus.Counter
+	queueLength        prometheus.Gauge
+	queueDropped       prometheus.Counter
+	spoolDropped       *prometheus.CounterVec
+	circuitState       *prometheus.GaugeVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		bytesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "remote_write_bytes_sent_total",
+			Help: "Total number of compressed payload bytes sent, by endpoint.",
+		}, []string{"endpoint"}),
+		samplesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "remote_write_samples_total",
+			Help: "Total number of samples sent successfully, by endpoint.",
+		}, []string{"endpoint"}),
+		sendsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "remote_write_failed_total",
+			Help: "Total number of batches that could not be sent, by endpoint.",
+		}, []string{"endpoint"}),
+		sendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "remote_write_send_duration_seconds",
+			Help:    "Duration of individual send attempts, by endpoint.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		payloadBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "remote_write_payload_bytes",
+			Help: "Compressed size of the request body of each batch, by endpoint.",
+			// 1KiB to 16MiB.
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+		}, []string{"endpoint"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "remote_write_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful send, by endpoint.",
+		}, []string{"endpoint"}),
+		seriesDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "remote_write_series_dropped_total",
+			Help: "Total number of series dropped before sending, by reason.",
+		}, []string{"reason"}),
+		familiesSkipped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "remote_write_metric_families_skipped_total",
+			Help: "Total number of metric families skipped because they could not be converted.",
+		}),
+		duplicateSeries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "remote_write_duplicate_series_total",
+			Help: "Total number of series merged because another series in the same batch had the same label set, by metric name.",
+		}, []string{"name"}),
+		maxLabelCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "remote_write_series_max_label_count",
+			Help: "Highest number of labels on a single series in the last gather.",
+		}),
+		labelCountExceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "remote_write_series_label_count_exceeded_total",
+			Help: "Total number of series with more labels than the configured threshold.",
+		}),
+		quotaRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "remote_write_sample_quota_remaining",
+			Help: "Number of samples left in the current sample quota window.",
+		}),
+		shards: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "remote_write_shards",
+			Help: "Number of shards used to send batches concurrently, by endpoint.",
+		}, []string{"endpoint"}),
+		desiredShards: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "remote_write_shards_desired",
+			Help: "Number of shards the adaptive shard scaling wants to run, by endpoint.",
+		}, []string{"endpoint"}),
+		missedTicks: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "remote_write_missed_ticks_total",
+			Help: "Total number of ticks skipped because the previous send cycle was still running.",
+		}),
+		queueLength: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "remote_write_queue_length",
+			Help: "Number of gathers waiting to be sent.",
+		}),
+		queueDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "remote_write_queue_dropped_total",
+			Help: "Total number of gathers dropped because the send queue was full or over its byte budget.",
+		}),
+		spoolDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "remote_write_spool_dropped_total",
+			Help: "Total number of spooled payloads dropped because the spool was full, by endpoint.",
+		}, []string{"endpoint"}),
+		circuitState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "remote_write_circuit_breaker_state",
+			Help: "State of the circuit breaker, by endpoint: 0 closed, 1 open, 2 half-open.",
+		}, []string{"endpoint"}),
+	}
+}
+
+func (m *metrics) register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.bytesSent,
+		m.samplesSent,
+		m.sendsFailed,
+		m.sendDuration,
+		m.payloadBytes,
+		m.lastSuccess,
+		m.seriesDropped,
+		m.familiesSkipped,
+		m.duplicateSeries,
+		m.maxLabelCount,
+		m.labelCountExceeded,
+		m.quotaRemaining,
+		m.shards,
+		m.desiredShards,
+		m.missedTicks,
+		m.queueLength,
+		m.queueDropped,
+		m.spoolDropped,
+		m.circuitState,
+	} {
+		if err := reg.Register(c); err != nil {
+			return fmt.Errorf("failed to register self metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// WithSelfMetricsRegisterer registers the writer's own metrics on reg. They
+// are not registered anywhere by default. This is independent of
+// WithGatherer, which selects the metrics that are sent: if reg is the
+// registry being gathered, such as prometheus.DefaultRegisterer together with
+// the default gatherer, the writer sends its own metrics along with the
+// application's, each send reporting on the one before. Use a separate
+// registry to expose them only locally.
+func WithSelfMetricsRegisterer(reg prometheus.Registerer) Option {
+	return func(c *Client) {
+		c.registerer = reg
+	}
+}
+
+// WithEndpointName sets the value of the endpoint label on the writer's own
+// metrics. It defaults to the remote write URL without its credentials.
+func WithEndpointName(name string) Option {
+	return func(c *Client) {
+		c.endpointName = name
+	}
+}
@@ -0,0 +1,117 @@
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// NameValidation controls how metric and label names that violate the
+// Prometheus naming rules are handled. Metric names must match
+// [a-zA-Z_:][a-zA-Z0-9_:]* and label names [a-zA-Z_][a-zA-Z0-9_]*.
+type NameValidation int
+
+const (
+	// NameValidationNone sends names unchanged. This is the default.
+	NameValidationNone NameValidation = iota
+	// NameValidationSanitize replaces invalid characters with underscores.
+	NameValidationSanitize
+	// NameValidationStrict rejects invalid names: New fails for an invalid
+	// name prefix or external label name, and a metric family with an
+	// invalid name is skipped.
+	NameValidationStrict
+)
+
+// WithNameValidation sets how invalid metric and label names are handled.
+// It mostly guards names built from WithNamePrefix and WithExternalLabels.
+func WithNameValidation(v NameValidation) Option {
+	return func(c *Client) {
+		c.nameValidation = v
+	}
+}
+
+// validateNameOptions checks the configured names that end up in every
+// series when strict validation is enabled.
+func (c *Client) validateNameOptions() error {
+	if c.nameValidation != NameValidationStrict {
+		return nil
+	}
+
+	var errs []error
+	if c.namePrefix != "" && !validMetricName(c.namePrefix) {
+		errs = append(errs, fmt.Errorf("invalid metric name prefix %q", c.namePrefix))
+	}
+	for _, l := range c.externalLabels {
+		if !validLabelName(l.Name) {
+			errs = append(errs, fmt.Errorf("invalid external label name %q", l.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkNames applies the name validation to the labels of series.
+func (c *Client) checkNames(series []prompb.TimeSeries) error {
+	if c.nameValidation == NameValidationNone {
+		return nil
+	}
+
+	for i := range series {
+		labels := series[i].Labels
+		changed := false
+		for j, l := range labels {
+			if l.Name == "__name__" {
+				if validMetricName(l.Value) {
+					continue
+				}
+				if c.nameValidation == NameValidationStrict {
+					return fmt.Errorf("invalid metric name %q", l.Value)
+				}
+				labels[j].Value = sanitizeName(l.Value, true)
+				continue
+			}
+
+			if validLabelName(l.Name) {
+				continue
+			}
+			if c.nameValidation == NameValidationStrict {
+				return fmt.Errorf("invalid label name %q", l.Name)
+			}
+			labels[j].Name = sanitizeName(l.Name, false)
+			changed = true
+		}
+		if changed {
+			sortLabels(labels)
+		}
+	}
+	return nil
+}
+
+func validMetricName(name string) bool {
+	return name != "" && sanitizeName(name, true) == name
+}
+
+func validLabelName(name string) bool {
+	return name != "" && sanitizeName(name, false) == name
+}
+
+// sanitizeName replaces the characters of name that are not allowed in a
+// metric name, or a label name if metric is false, with underscores. A
+// leading digit is also replaced.
+func sanitizeName(name string, metric bool) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for i, r := range name {
+		valid := r == '_' ||
+			(r >= 'a' && r <= 'z') ||
+			(r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9' && i > 0) ||
+			(r == ':' && metric)
+		if !valid {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
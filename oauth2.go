@@ -0,0 +1,60 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth2Config configures the OAuth 2.0 client credentials flow.
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+	Scopes       []string
+}
+
+// WithOAuth2 authenticates every request with an access token obtained from
+// TokenURL using the client credentials flow. Tokens are cached and
+// refreshed before they expire. Token requests use the same transport as
+// remote write requests.
+func WithOAuth2(cfg OAuth2Config) Option {
+	return func(c *Client) {
+		c.oauth2 = &cfg
+	}
+}
+
+func (cfg *OAuth2Config) validate() error {
+	if cfg.ClientID == "" || cfg.TokenURL == "" {
+		return errors.New("OAuth2 client ID and token URL are required")
+	}
+	return nil
+}
+
+// wrapOAuth2 makes the HTTP client attach access tokens when OAuth2 is
+// configured. A client supplied with WithHTTPClient is copied rather than
+// modified.
+func (c *Client) wrapOAuth2() {
+	if c.oauth2 == nil {
+		return
+	}
+
+	next := c.httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	cc := &clientcredentials.Config{
+		ClientID:     c.oauth2.ClientID,
+		ClientSecret: c.oauth2.ClientSecret,
+		TokenURL:     c.oauth2.TokenURL,
+		Scopes:       c.oauth2.Scopes,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: next})
+
+	client := *c.httpClient
+	client.Transport = &oauth2.Transport{Source: cc.TokenSource(ctx), Base: next}
+	c.httpClient = &client
+}
@@ -0,0 +1,52 @@
+package remotewrite
+
+import (
+	"context"
+	"time"
+)
+
+// SendResult describes a single attempt to send a batch.
+type SendResult struct {
+	// Endpoint is the endpoint name, as set by WithEndpointName.
+	Endpoint string
+	// Series is the number of series in the batch.
+	Series int
+	// Bytes is the size of the compressed request body.
+	Bytes int
+	// StatusCode is the HTTP status of the response, or zero if there was
+	// none, such as on a network error or when sending to a sink.
+	StatusCode int
+	// Err is the error of the attempt, or nil if it succeeded.
+	Err error
+	// Duration is how long the attempt took.
+	Duration time.Duration
+}
+
+// WithOnSend calls fn after every attempt to send a batch, including
+// retries, for custom telemetry or assertions in tests. fn may be called
+// concurrently when batches are sent by several shards or endpoints.
+func WithOnSend(fn func(SendResult)) Option {
+	return func(c *Client) {
+		c.onSend = fn
+	}
+}
+
+// attempt delivers p, which holds series series, and reports the result to
+// the OnSend callback.
+func (c *Client) attempt(ctx context.Context, p Payload, series int) error {
+	start := time.Now()
+	code, err := c.deliver(ctx, p)
+	if c.onSend == nil {
+		return err
+	}
+
+	c.onSend(SendResult{
+		Endpoint:   c.endpointName,
+		Series:     series,
+		Bytes:      len(p.Body),
+		StatusCode: code,
+		Err:        err,
+		Duration:   time.Since(start),
+	})
+	return err
+}
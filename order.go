@@ -0,0 +1,59 @@
+package remotewrite
+
+import (
+	"sort"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// SeriesOrder is the order in which the series of a summary or classic
+// histogram are written. Whatever the order, the series of a metric are
+// written together, quantiles and buckets by ascending bound, and its
+// _created series comes last.
+type SeriesOrder int
+
+const (
+	// SeriesOrderPrometheus writes quantiles or buckets, then _sum and then
+	// _count, as in the Prometheus text format. It is the default.
+	SeriesOrderPrometheus SeriesOrder = iota
+	// SeriesOrderOpenMetrics writes quantiles or buckets, then _count and
+	// then _sum, as in the OpenMetrics text format.
+	SeriesOrderOpenMetrics
+)
+
+// WithSeriesOrder sets the order of the series generated for each summary
+// and classic histogram, for receivers that check it. The default is
+// SeriesOrderPrometheus.
+func WithSeriesOrder(o SeriesOrder) Option {
+	return func(c *Client) {
+		c.seriesOrder = o
+	}
+}
+
+// sortedQuantiles returns qs ordered by ascending quantile, copying it only
+// if it is out of order.
+func sortedQuantiles(qs []*io_prometheus_client.Quantile) []*io_prometheus_client.Quantile {
+	less := func(qs []*io_prometheus_client.Quantile) func(i, j int) bool {
+		return func(i, j int) bool { return qs[i].GetQuantile() < qs[j].GetQuantile() }
+	}
+	if sort.SliceIsSorted(qs, less(qs)) {
+		return qs
+	}
+	qs = append([]*io_prometheus_client.Quantile(nil), qs...)
+	sort.SliceStable(qs, less(qs))
+	return qs
+}
+
+// sortedBuckets returns bs ordered by ascending upper bound, copying it only
+// if it is out of order.
+func sortedBuckets(bs []*io_prometheus_client.Bucket) []*io_prometheus_client.Bucket {
+	less := func(bs []*io_prometheus_client.Bucket) func(i, j int) bool {
+		return func(i, j int) bool { return bs[i].GetUpperBound() < bs[j].GetUpperBound() }
+	}
+	if sort.SliceIsSorted(bs, less(bs)) {
+		return bs
+	}
+	bs = append([]*io_prometheus_client.Bucket(nil), bs...)
+	sort.SliceStable(bs, less(bs))
+	return bs
+}
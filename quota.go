@@ -0,0 +1,66 @@
+package remotewrite
+
+import (
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithSampleQuota caps the number of samples sent to n per fixed window.
+// Once the quota for the current window is used up, further series are
+// dropped, not delayed, until the next window starts: data is lost when the
+// quota is hit. Samples count against the quota when they are handed to the
+// sender, whether or not the send succeeds.
+func WithSampleQuota(n int, window time.Duration) Option {
+	return func(c *Client) {
+		c.quota = &sampleQuota{limit: n, window: window}
+	}
+}
+
+// sampleQuota tracks how many samples remain in the current window.
+type sampleQuota struct {
+	limit  int
+	window time.Duration
+
+	start     time.Time
+	remaining int
+}
+
+// take returns the series that fit in the remaining quota, in order, and the
+// number of series dropped.
+func (q *sampleQuota) take(series []prompb.TimeSeries, now time.Time) ([]prompb.TimeSeries, int) {
+	if q.start.IsZero() || !now.Before(q.start.Add(q.window)) {
+		q.start = now
+		q.remaining = q.limit
+	}
+
+	dropped := 0
+	out := series[:0]
+	for _, ts := range series {
+		n := len(ts.Samples) + len(ts.Histograms)
+		if n > q.remaining {
+			dropped++
+			continue
+		}
+		q.remaining -= n
+		out = append(out, ts)
+	}
+
+	return out, dropped
+}
+
+// applyQuota drops the series that exceed the sample quota.
+func (c *Client) applyQuota(series []prompb.TimeSeries) []prompb.TimeSeries {
+	if c.quota == nil {
+		return series
+	}
+
+	series, dropped := c.quota.take(series, time.Now())
+	if dropped > 0 {
+		c.metrics.seriesDropped.WithLabelValues("quota").Add(float64(dropped))
+		c.logger.Warn("sample quota exhausted, dropping series", "dropped", dropped)
+	}
+	c.metrics.quotaRemaining.Set(float64(c.quota.remaining))
+
+	return series
+}
@@ -2,29 +2,36 @@ package remotewrite
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/golang/protobuf/proto"
-	"github.com/golang/snappy"
-	"github.com/prometheus/client_golang/prometheus"
 	io_prometheus_client "github.com/prometheus/client_model/go"
 	"github.com/prometheus/prometheus/prompb"
 )
 
-func sendToRemoteWrite(data *bytes.Buffer, remoteWriteURL string) (*http.Response, error) {
-	req, err := http.NewRequest("POST", remoteWriteURL, data)
+func (c *Client) sendToRemoteWrite(ctx context.Context, data *bytes.Buffer, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	req.Header.Set("Content-Encoding", "snappy")
-	req.Header.Set("Content-Type", "application/x-protobuf")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if err := c.authorize(req); err != nil {
+		return nil, fmt.Errorf("failed to authorize HTTP request: %w", err)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 	}
@@ -32,91 +39,639 @@ func sendToRemoteWrite(data *bytes.Buffer, remoteWriteURL string) (*http.Respons
 	return resp, nil
 }
 
-func createSnappyWithMetricFamily(mfs []*io_prometheus_client.MetricFamily) (*prompb.WriteRequest, error) {
+// ToTimeSeries converts metric families to remote write time series without
+// any Client options applied. Samples without an explicit timestamp are
+// stamped with ts, in milliseconds since the epoch. Families that cannot be
+// converted are skipped.
+func ToTimeSeries(mfs []*io_prometheus_client.MetricFamily, ts int64) []prompb.TimeSeries {
+	var (
+		c   Client
+		out []prompb.TimeSeries
+	)
+	for _, mf := range mfs {
+		series, err := c.convertFamily(mf, ts)
+		if err != nil {
+			continue
+		}
+		out = append(out, series...)
+	}
+	return out
+}
+
+// BuildWriteRequest converts metric families to a write request the way the
+// Client does before sending, applying its label, filtering and naming
+// options. It updates the Client's series state, such as series TTLs, so it
+// should not be mixed with sends from the same Client. Calls are serialized
+// with gathers.
+func (c *Client) BuildWriteRequest(mfs []*io_prometheus_client.MetricFamily) (*prompb.WriteRequest, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.createSnappyWithMetricFamily(mfs)
+}
+
+func (c *Client) createSnappyWithMetricFamily(mfs []*io_prometheus_client.MetricFamily) (*prompb.WriteRequest, error) {
 	var ts []prompb.TimeSeries
-	tStamp := time.Now().UnixNano() / int64(time.Millisecond)
+	tStamp := c.nowMillis()
+
+	if c.ttl != nil {
+		c.ttl.begin()
+		defer c.ttl.sweep()
+	}
+	if c.duplicates != nil {
+		c.duplicates.begin()
+		defer c.duplicates.sweep()
+	}
+	if c.metadata != nil {
+		c.metadata.begin()
+		defer c.metadata.commit()
+	}
 
 	for _, mf := range mfs {
-		for _, m := range mf.Metric {
-			labels := []prompb.Label{
-				{Name: "__name__", Value: mf.GetName()},
-			}
-			for _, lp := range m.Label {
-				labels = append(labels, prompb.Label{
-					Name:  lp.GetName(),
-					Value: lp.GetValue(),
-				})
+		series, err := c.convertFamily(mf, tStamp)
+		if err != nil {
+			c.logger.Warn("skipping metric family", "name", mf.GetName(), "err", err)
+			c.metrics.familiesSkipped.Inc()
+			continue
+		}
+		if c.metadata != nil {
+			c.addMetadata(series, mf)
+		}
+		ts = append(ts, series...)
+	}
+
+	ts = c.checkLabelLengths(ts)
+
+	// Receivers reject requests with duplicate series, which collectors
+	// with overlapping output or dropped labels can produce.
+	ts = c.dedupSeries(ts)
+
+	ts = c.checkLabelCounts(ts)
+
+	if c.staleness != nil {
+		ts = c.staleness.markStale(ts, tStamp)
+	}
+
+	c.shiftTimestamps(ts)
+
+	return &prompb.WriteRequest{Timeseries: ts}, nil
+}
+
+// convertFamily converts a single metric family into time series. A panic
+// caused by malformed input is turned into an error so that one bad family
+// does not fail the whole send.
+func (c *Client) convertFamily(mf *io_prometheus_client.MetricFamily, tStamp int64) (ts []prompb.TimeSeries, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ts, err = nil, fmt.Errorf("panic during conversion: %v", r)
+		}
+	}()
+
+	for _, m := range mf.Metric {
+		if !c.keepMetric(mf.GetName(), m) {
+			continue
+		}
+
+		var metricLabels []prompb.Label
+		for _, lp := range m.Label {
+			// An empty value is equivalent to the label being absent.
+			if lp.GetValue() == "" || !c.keepLabel(lp.GetName()) {
+				continue
 			}
+			metricLabels = append(metricLabels, prompb.Label{
+				Name:  lp.GetName(),
+				Value: lp.GetValue(),
+			})
+		}
+		metricLabels = c.withExternalLabels(metricLabels)
 
-			var samples []prompb.Sample
-			value := 0.0
-			switch *mf.Type {
-			case io_prometheus_client.MetricType_COUNTER:
-				value = m.GetCounter().GetValue()
-			case io_prometheus_client.MetricType_GAUGE:
-				value = m.GetGauge().GetValue()
-			case io_prometheus_client.MetricType_UNTYPED:
-				value = m.GetUntyped().GetValue()
-			case io_prometheus_client.MetricType_SUMMARY:
-				value = m.GetSummary().GetSampleSum()
-			case io_prometheus_client.MetricType_HISTOGRAM:
-				value = m.GetHistogram().GetSampleSum()
+		// Explicit timestamps, such as those of federated samples,
+		// take precedence over the gather time.
+		sampleTs := tStamp
+		if m.GetTimestampMs() != 0 {
+			sampleTs = m.GetTimestampMs()
+		}
 
-			default:
-				log.Fatalf("Unknown metric type: %v", *mf.Type)
+		// seriesLabels returns the sorted labels of a series named name
+		// carrying the metric's labels, plus extra if it is not nil.
+		seriesLabels := func(name string, extra *prompb.Label) []prompb.Label {
+			labels := make([]prompb.Label, 0, len(metricLabels)+2)
+			labels = append(labels, prompb.Label{Name: "__name__", Value: name})
+			labels = append(labels, metricLabels...)
+			if extra != nil {
+				labels = append(labels, *extra)
 			}
+			sortLabels(labels)
+			return labels
+		}
+
+		// emit appends a float series named name carrying the metric's
+		// labels, plus extra if it is not nil, and the given exemplars.
+		emit := func(name string, extra *prompb.Label, value float64, exemplars ...*io_prometheus_client.Exemplar) {
+			labels := seriesLabels(name, extra)
 
-			samples = append(samples, prompb.Sample{
+			sample := prompb.Sample{
 				Value:     value,
-				Timestamp: tStamp,
-			})
+				Timestamp: sampleTs,
+			}
+
+			if c.ttl != nil && mf.GetType() == io_prometheus_client.MetricType_GAUGE {
+				if !c.ttl.apply(seriesKey(labels), &sample) {
+					return
+				}
+			}
+
+			if c.duplicates != nil && !c.duplicates.fresh(seriesKey(labels), sample) {
+				return
+			}
 
 			ts = append(ts, prompb.TimeSeries{
-				Labels:  labels,
-				Samples: samples,
+				Labels:    labels,
+				Samples:   []prompb.Sample{sample},
+				Exemplars: c.convertExemplars(exemplars, sampleTs),
 			})
 		}
+
+		// totals appends the _sum and _count series of a summary or
+		// classic histogram in the configured order.
+		totals := func(name string, sum, count float64) {
+			if c.seriesOrder == SeriesOrderOpenMetrics {
+				emit(c.seriesName(name, "_count"), nil, count)
+				emit(c.seriesName(name, "_sum"), nil, sum)
+				return
+			}
+			emit(c.seriesName(name, "_sum"), nil, sum)
+			emit(c.seriesName(name, "_count"), nil, count)
+		}
+
+		name := c.namePrefix + mf.GetName()
+		metricName := c.seriesName(name, "")
+		switch *mf.Type {
+		case io_prometheus_client.MetricType_COUNTER:
+			emit(metricName, nil, m.GetCounter().GetValue(), m.GetCounter().GetExemplar())
+		case io_prometheus_client.MetricType_GAUGE:
+			emit(metricName, nil, m.GetGauge().GetValue())
+		case io_prometheus_client.MetricType_UNTYPED:
+			emit(metricName, nil, m.GetUntyped().GetValue())
+		case io_prometheus_client.MetricType_SUMMARY:
+			sum := m.GetSummary()
+			for _, q := range sortedQuantiles(sum.GetQuantile()) {
+				emit(metricName, &prompb.Label{Name: "quantile", Value: c.formatBound(q.GetQuantile())}, q.GetValue())
+			}
+			totals(name, sum.GetSampleSum(), float64(sum.GetSampleCount()))
+		case io_prometheus_client.MetricType_HISTOGRAM, io_prometheus_client.MetricType_GAUGE_HISTOGRAM:
+			h := m.GetHistogram()
+			if isNativeHistogram(h) {
+				gauge := mf.GetType() == io_prometheus_client.MetricType_GAUGE_HISTOGRAM
+				ts = append(ts, prompb.TimeSeries{
+					Labels:     seriesLabels(metricName, nil),
+					Histograms: []prompb.Histogram{nativeHistogram(h, sampleTs, gauge)},
+					Exemplars:  c.convertExemplars(h.GetExemplars(), sampleTs),
+				})
+				break
+			}
+
+			count := float64(h.GetSampleCount())
+			if h.SampleCountFloat != nil {
+				count = h.GetSampleCountFloat()
+			}
+
+			infSeen := false
+			for _, b := range sortedBuckets(h.GetBucket()) {
+				upper := b.GetUpperBound()
+				if math.IsInf(upper, +1) {
+					infSeen = true
+				}
+				cumulative := float64(b.GetCumulativeCount())
+				if b.CumulativeCountFloat != nil {
+					cumulative = b.GetCumulativeCountFloat()
+				}
+				emit(c.seriesName(name, "_bucket"), &prompb.Label{Name: "le", Value: c.formatBound(upper)}, cumulative, b.GetExemplar())
+			}
+			// client_golang leaves the +Inf bucket implicit; it always
+			// holds every observation.
+			if !infSeen {
+				emit(c.seriesName(name, "_bucket"), &prompb.Label{Name: "le", Value: c.formatBound(math.Inf(+1))}, count)
+			}
+			totals(name, h.GetSampleSum(), count)
+
+		default:
+			return nil, fmt.Errorf("unknown metric type %v", mf.GetType())
+		}
+
+		if c.createdSeries {
+			if created := createdTimestamp(m); created != nil {
+				emit(c.createdName(name), nil, float64(created.AsTime().UnixMilli())/1000)
+			}
+		}
 	}
 
-	fmt.Printf("Writing %v metrics at time: %v\n", len(ts), tStamp)
-	return &prompb.WriteRequest{Timeseries: ts}, nil
+	if err := c.checkNames(ts); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// formatFloat formats bucket bounds and quantiles the way Prometheus does.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// collectionDurationSeries returns the series reporting how long gathering
+// and conversion took.
+func (c *Client) collectionDurationSeries(d time.Duration, ts int64) prompb.TimeSeries {
+	labels := c.withExternalLabels([]prompb.Label{
+		{Name: "__name__", Value: "remote_write_collection_duration_seconds"},
+	})
+	sortLabels(labels)
+
+	return prompb.TimeSeries{
+		Labels: labels,
+		Samples: []prompb.Sample{{
+			Value:     d.Seconds(),
+			Timestamp: ts,
+		}},
+	}
 }
 
-func RemoteWrite(remoteWriteURL string, frequency time.Duration) {
+// RemoteWrite gathers metrics from prometheus.DefaultGatherer every frequency
+// and sends them to remoteWriteURL. It only returns if the client cannot be
+// created or frequency is not positive; failed sends are logged and retried
+// on the next tick.
+func RemoteWrite(remoteWriteURL string, frequency time.Duration) error {
+	return RemoteWriteWithContext(context.Background(), remoteWriteURL, frequency)
+}
+
+// RemoteWriteWithContext is like RemoteWrite but stops when ctx is cancelled,
+// returning nil.
+func RemoteWriteWithContext(ctx context.Context, remoteWriteURL string, frequency time.Duration) error {
+	c, err := New(remoteWriteURL)
+	if err != nil {
+		return fmt.Errorf("failed to create remote write client: %w", err)
+	}
+
+	return c.Run(ctx, frequency)
+}
+
+// RemoteWrite gathers and sends metrics every frequency until the process
+// exits. It returns right away, logging the error, if frequency is not
+// positive. See Run.
+func (c *Client) RemoteWrite(frequency time.Duration) {
+	if err := c.Run(context.Background(), frequency); err != nil {
+		c.logger.Error("remote write stopped", "err", err)
+	}
+}
+
+// Run gathers and sends metrics every frequency until ctx is cancelled. The
+// first send happens immediately unless WithWaitFirstTick is set. A failed
+// send is logged and does not stop the loop. Sends never overlap. By default,
+// ticks that fire while a send is still running are skipped and counted in
+// remote_write_missed_ticks_total; see WithBackpressure for queueing them.
+//
+// Run returns an error right away if frequency is not positive. A frequency
+// below MinFrequency is raised to it, so a typo cannot turn the loop into a
+// hot loop of gathers and requests.
+//
+// When ctx is cancelled, an in-flight request is aborted and Run returns nil,
+// or the result of the final send if WithFlushOnShutdown is set.
+func (c *Client) Run(ctx context.Context, frequency time.Duration) error {
+	return c.run(ctx, frequency, func(error) {})
+}
+
+// RunWithErrors runs Run in a new goroutine and returns a channel that
+// receives the outcome of every tick: nil on success, or the error that Run
+// would log. With BackpressureQueue, the outcome of a tick is delivered once
+// its queued gather has been sent. A failed final send from
+// WithFlushOnShutdown is reported too. The channel buffers 16 outcomes; while
+// it is full because the receiver falls behind, further outcomes are dropped
+// rather than stalling the loop. The channel is closed once Run returns after
+// ctx is cancelled.
+func (c *Client) RunWithErrors(ctx context.Context, frequency time.Duration) <-chan error {
+	errs := make(chan error, 16)
+	report := func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+
+	go func() {
+		defer close(errs)
+		if err := c.run(ctx, frequency, report); err != nil {
+			report(err)
+		}
+	}()
+	return errs
+}
+
+// MinFrequency is the shortest interval Run sends at.
+const MinFrequency = 100 * time.Millisecond
+
+// run implements Run, passing the outcome of every tick to report.
+func (c *Client) run(ctx context.Context, frequency time.Duration, report func(error)) error {
+	if frequency <= 0 {
+		return fmt.Errorf("frequency must be positive, got %v", frequency)
+	}
+	if frequency < MinFrequency {
+		c.logger.Warn("frequency too short, using the minimum", "frequency", frequency, "minimum", MinFrequency)
+		frequency = MinFrequency
+	}
+
+	// Adaptive sharding and the Retry-After limit of every endpoint are
+	// based on the interval. Sends read it under sendMu, and a concurrent
+	// Flush may already be sending.
+	c.sendMu.Lock()
+	c.interval = frequency
+	for _, e := range c.endpoints {
+		e.interval = frequency
+	}
+	c.sendMu.Unlock()
+
+	if c.startJitter {
+		if !sleep(ctx, time.Duration(rand.Int63n(int64(frequency)))) {
+			return c.shutdown(ctx)
+		}
+	}
+
 	ticker := time.NewTicker(frequency)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		m, err := prometheus.DefaultGatherer.Gather()
-		if err != nil {
-			log.Fatalf("Failed to gather metrics: %v", err)
+	if c.waitFirstTick {
+		select {
+		case <-ctx.Done():
+			return c.shutdown(ctx)
+		case <-ticker.C:
 		}
+	}
 
-		wr, err := createSnappyWithMetricFamily(m)
-		if err != nil {
-			log.Fatalf("Failed to create snappy with metric family: %v", err)
-		}
+	var queue *sendQueue
+	if c.backpressure == BackpressureQueue {
+		queue = c.startQueue(ctx, report)
+	}
 
-		data, err := proto.Marshal(wr)
-		if err != nil {
-			log.Fatalf("unable to marshal protobuf: %v", err)
+	for {
+		start := time.Now()
+		queued, err := c.tick(ctx, queue)
+		// The sender reports the outcome of a queued gather.
+		if ctx.Err() == nil && !queued {
+			if err != nil {
+				c.logSendError(err)
+			}
+			report(err)
 		}
 
-		buf := &bytes.Buffer{}
-		buf.Write(snappy.Encode(nil, data))
+		// Ticks that fired while this cycle was running are skipped
+		// rather than starting a new gather right away.
+		if missed := int(time.Since(start) / frequency); missed > 0 {
+			c.metrics.missedTicks.Add(float64(missed))
+			c.logger.Warn("send cycle overran frequency, skipping ticks", "missed", missed)
+			select {
+			case <-ticker.C:
+			default:
+			}
+		}
 
-		resp, err := sendToRemoteWrite(buf, remoteWriteURL)
-		if err != nil {
-			log.Fatalf("Failed to send data to remote write endpoint: %v", err)
+		select {
+		case <-ctx.Done():
+			queue.stop()
+			return c.shutdown(ctx)
+		case <-ticker.C:
 		}
+	}
+}
+
+// tick performs the work of a single tick: a full send, or with a queue,
+// a gather whose result is queued for sending. It reports whether a gather
+// was queued.
+func (c *Client) tick(ctx context.Context, queue *sendQueue) (bool, error) {
+	if queue == nil {
+		return false, c.WriteOnce(ctx)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	series, err := c.collect()
+	if err != nil || len(series) == 0 {
+		return false, err
+	}
+	queue.push(newQueuedGather(series, nil))
+	return true, nil
+}
+
+// shutdown performs the final send requested by WithFlushOnShutdown.
+func (c *Client) shutdown(ctx context.Context) error {
+	if c.flushTimeout <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.flushTimeout)
+	defer cancel()
+
+	return c.WriteOnce(ctx)
+}
+
+// WriteOnce gathers metrics once and sends them to remoteWriteURL. It suits
+// batch jobs that push their metrics a single time before exiting.
+func WriteOnce(remoteWriteURL string) error {
+	c, err := New(remoteWriteURL)
+	if err != nil {
+		return fmt.Errorf("failed to create remote write client: %w", err)
+	}
+
+	return c.WriteOnce(context.Background())
+}
+
+// Flush immediately gathers and sends metrics, for example in a deferred call
+// before a process exits. It is safe to call while Run is active: sends are
+// serialized, and each one gathers afresh, so no batch is sent twice.
+func (c *Client) Flush(ctx context.Context) error {
+	return c.WriteOnce(ctx)
+}
+
+// WriteOnce gathers metrics once and sends them. If any batch fails, the
+// returned error reports every failure. Run calls it on every tick. Calls are
+// serialized. While Run queues gathers with BackpressureQueue, the gather is
+// sent behind the queued ones.
+func (c *Client) WriteOnce(ctx context.Context) error {
+	c.writeMu.Lock()
+	series, err := c.collect()
+	if err != nil || len(series) == 0 {
+		c.writeMu.Unlock()
+		return err
+	}
+
+	if q := c.queue; q != nil {
+		// Run queues its gathers; sending this one behind them keeps
+		// batches in the order they were gathered.
+		result := make(chan error, 1)
+		q.push(newQueuedGather(series, result))
+		c.writeMu.Unlock()
+		return q.wait(ctx, result)
+	}
+
+	// Take the send lock before releasing the collect lock, so that
+	// batches are sent in the order they were gathered.
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.writeMu.Unlock()
+
+	return c.sendAll(ctx, series)
+}
+
+// collect gathers and converts metrics into the series to send. It returns
+// no series when there is nothing to send. The caller must hold writeMu.
+func (c *Client) collect() ([]prompb.TimeSeries, error) {
+	start := time.Now()
+
+	m, err := c.gatherer.Gather()
+	if err != nil && len(m) == 0 {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	if err != nil {
+		// Some collectors failed; send what the others gathered.
+		c.logger.Warn("partial gather failure", "err", err, "families", len(m))
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			log.Fatalf("Unexpected response status: %s", resp.Status)
+	if len(m) == 0 {
+		return nil, c.handleEmptyGather()
+	}
+	c.gathered = true
+
+	wr, err := c.createSnappyWithMetricFamily(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snappy with metric family: %w", err)
+	}
+
+	if c.collectionDuration {
+		wr.Timeseries = append(wr.Timeseries, c.collectionDurationSeries(time.Since(start), c.nowMillis()+c.timestampOffset.Milliseconds()))
+	}
+
+	if c.dryRun != nil {
+		c.dryRun(wr)
+		return nil, nil
+	}
+
+	if err := c.saveState(); err != nil {
+		c.logger.Warn("failed to persist series state", "err", err)
+	}
+
+	wr.Timeseries = c.applyQuota(wr.Timeseries)
+	if len(wr.Timeseries) == 0 {
+		c.logger.Debug("no series to send, skipping")
+	}
+
+	return wr.Timeseries, nil
+}
+
+// maxErrorBodySize caps how much of an error response body is read.
+const maxErrorBodySize = 4 << 10
+
+// statusError is returned when the endpoint responds with a status outside
+// 2xx.
+type statusError struct {
+	code   int
+	status string
+	body   string
+
+	// retryAfter is the delay requested by a Retry-After header.
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("unexpected response status: %s", e.status)
+	}
+	return fmt.Sprintf("unexpected response status: %s: %s", e.status, e.body)
+}
+
+// statusCode returns the HTTP status code carried by err, or 0 if there is
+// none.
+func statusCode(err error) int {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code
+	}
+	return 0
+}
+
+// encode marshals and compresses a single write request on behalf of tenant,
+// which may be empty.
+func (c *Client) encode(wr *prompb.WriteRequest, tenant string) (Payload, error) {
+	buf := c.getBuffers()
+	defer c.putBuffers(buf)
+
+	data, err := c.marshal(buf.marshal, wr)
+	if err != nil {
+		return Payload{}, fmt.Errorf("unable to marshal protobuf: %w", err)
+	}
+	buf.marshal = data
+
+	compressed, err := c.compress(buf.compress, data)
+	if err != nil {
+		return Payload{}, fmt.Errorf("unable to compress payload: %w", err)
+	}
+	buf.compress = compressed
+
+	header := c.headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if enc := c.contentEncoding(); enc != "" {
+		header.Set("Content-Encoding", enc)
+	}
+	header.Set("Content-Type", c.contentType())
+	header.Set(versionHeader, c.version())
+	if tenant != "" {
+		header.Set(tenantHeader, tenant)
+	}
+
+	return Payload{Body: bytes.Clone(compressed), Header: header}, nil
+}
+
+// deliver sends an encoded payload to the sink or the remote write endpoint.
+// It returns the HTTP status of the response, or zero if there was none. Any
+// 2xx status is a success; Prometheus answers 204 No Content.
+func (c *Client) deliver(ctx context.Context, p Payload) (int, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		c.metrics.sendDuration.WithLabelValues(c.endpointName).Observe(time.Since(start).Seconds())
+	}()
+
+	if c.sink != nil {
+		if err := c.sink.Send(ctx, p); err != nil {
+			return 0, fmt.Errorf("failed to send data to sink: %w", err)
 		}
+		c.metrics.bytesSent.WithLabelValues(c.endpointName).Add(float64(len(p.Body)))
+		return 0, nil
+	}
 
-		resp.Body.Close()
-		log.Println("Data written successfully to Prometheus remote storage")
+	resp, err := c.sendToRemoteWrite(ctx, bytes.NewBuffer(p.Body), p.Header)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send data to remote write endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.metrics.bytesSent.WithLabelValues(c.endpointName).Add(float64(len(p.Body)))
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Receivers explain rejections, such as out of order samples,
+		// in the body.
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return resp.StatusCode, &statusError{
+			code:       resp.StatusCode,
+			status:     resp.Status,
+			body:       strings.TrimSpace(string(body)),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
+
+	c.logger.Debug("data written to remote storage", "bytes", len(p.Body))
+	return resp.StatusCode, nil
 }
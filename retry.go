@@ -0,0 +1,71 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRateLimitRetries bounds how often a batch is retried after 429 Too Many
+// Requests responses.
+const maxRateLimitRetries = 3
+
+// maxRetryAfterWait bounds the total time a batch waits for Retry-After
+// outside of Run, which instead bounds it by the send interval.
+const maxRetryAfterWait = time.Minute
+
+// retryAfterBudget returns how long a batch may wait in total for
+// Retry-After before it is given up, so one rate limited endpoint cannot
+// stall the send cycle.
+func (c *Client) retryAfterBudget() time.Duration {
+	if c.interval > 0 {
+		return c.interval
+	}
+	return maxRetryAfterWait
+}
+
+// parseRetryAfter parses a Retry-After header given either as a number of
+// seconds or as an HTTP date. It returns 0 if the header is missing or
+// invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// retryAfter returns how long err asks the sender to wait before retrying,
+// or 0 if it is not a rate limit response with a Retry-After header.
+func retryAfter(err error) time.Duration {
+	var se *statusError
+	if !errors.As(err, &se) || se.code != http.StatusTooManyRequests {
+		return 0
+	}
+	return se.retryAfter
+}
+
+// sleep waits for d or until ctx is done, reporting whether d elapsed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
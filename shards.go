@@ -0,0 +1,104 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithShards sends the batches produced by each gather over n concurrent
+// shards. The default is a single shard, which sends batches one at a time.
+// Only the batches of the BatchSplitter and of separate tenants are sent
+// concurrently, so with the default NoSplit and a single tenant every gather
+// is sent on one shard. A Sink used with more than one shard must be safe for
+// concurrent use.
+func WithShards(n int) Option {
+	return func(c *Client) {
+		c.shards = n
+	}
+}
+
+// WithAdaptiveShards scales the number of shards between minShards and
+// maxShards based on the send backlog, similar to Prometheus' queue manager.
+// After every send cycle the time the shards spent sending is compared with
+// half of the send interval: shards are added as soon as the backlog would
+// not be cleared in that budget and removed once fewer shards would do by a
+// clear margin. The initial number of shards is minShards. Like WithShards it
+// needs a BatchSplitter that produces several batches, and has no effect with
+// the default NoSplit.
+func WithAdaptiveShards(minShards, maxShards int) Option {
+	return func(c *Client) {
+		c.adaptiveShards = true
+		c.minShards = minShards
+		c.maxShards = maxShards
+		c.shards = minShards
+	}
+}
+
+// shrinkThreshold is how far below the current shard count the desired count
+// must fall before shards are removed, to avoid flapping.
+const shrinkThreshold = 0.7
+
+// reshard updates the number of shards after a cycle in which sending took
+// elapsed.
+func (c *Client) reshard(elapsed time.Duration) {
+	if !c.adaptiveShards || c.interval <= 0 {
+		return
+	}
+
+	budget := c.interval / 2
+	work := elapsed * time.Duration(c.shards)
+	desired := int(math.Ceil(float64(work) / float64(budget)))
+	desired = max(c.minShards, min(c.maxShards, desired))
+	c.metrics.desiredShards.WithLabelValues(c.endpointName).Set(float64(desired))
+
+	if desired > c.shards || float64(desired) < float64(c.shards)*shrinkThreshold {
+		c.logger.Debug("resharding", "from", c.shards, "to", desired)
+		c.shards = desired
+		c.metrics.shards.WithLabelValues(c.endpointName).Set(float64(desired))
+	}
+}
+
+// batchJob is a batch of series waiting to be sent for a tenant.
+type batchJob struct {
+	series []prompb.TimeSeries
+	tenant string
+}
+
+// sendBatches sends every job over the configured shards and returns the
+// errors of all batches that failed.
+func (c *Client) sendBatches(ctx context.Context, jobs []batchJob) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+
+	n := min(c.shards, len(jobs))
+	ch := make(chan batchJob)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range ch {
+				if err := c.sendBatch(ctx, job.series, job.tenant); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		ch <- job
+	}
+	close(ch)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
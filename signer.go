@@ -0,0 +1,39 @@
+package remotewrite
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// WithRequestSigner authenticates every request with the round tripper that
+// wrap builds around the Client's transport, for signing schemes that cover
+// the payload, such as the AWS SigV4 signing of the sigv4 package. Requests
+// are signed in the transport, after the body is compressed and all headers
+// are set. It counts as an authentication scheme.
+func WithRequestSigner(wrap func(next http.RoundTripper) (http.RoundTripper, error)) Option {
+	return func(c *Client) {
+		c.signer = wrap
+	}
+}
+
+// wrapSigner makes the HTTP client sign requests when a signer is configured.
+// A client supplied with WithHTTPClient is copied rather than modified.
+func (c *Client) wrapSigner() error {
+	if c.signer == nil {
+		return nil
+	}
+
+	next := c.httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	rt, err := c.signer(next)
+	if err != nil {
+		return fmt.Errorf("failed to set up request signing: %w", err)
+	}
+
+	client := *c.httpClient
+	client.Transport = rt
+	c.httpClient = &client
+	return nil
+}
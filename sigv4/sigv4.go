@@ -0,0 +1,41 @@
+// Package sigv4 signs remote write requests with AWS Signature Version 4, as
+// required by Amazon Managed Service for Prometheus. It is kept out of the
+// remotewrite package so that only users of SigV4 depend on the AWS SDK.
+package sigv4
+
+import (
+	"net/http"
+
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/sigv4"
+
+	prw "github.com/pree-dew/prometheus-remote-write"
+)
+
+// Config configures SigV4 signing. When AccessKey and SecretKey are empty,
+// credentials come from the default AWS credential chain.
+type Config struct {
+	Region    string
+	AccessKey string
+	SecretKey string
+	Profile   string
+	RoleARN   string
+}
+
+// With returns an option that signs every request of a remotewrite.Client
+// with SigV4. An invalid cfg makes remotewrite.New fail.
+func With(cfg Config) prw.Option {
+	return prw.WithRequestSigner(func(next http.RoundTripper) (http.RoundTripper, error) {
+		c := &sigv4.SigV4Config{
+			Region:    cfg.Region,
+			AccessKey: cfg.AccessKey,
+			SecretKey: config.Secret(cfg.SecretKey),
+			Profile:   cfg.Profile,
+			RoleARN:   cfg.RoleARN,
+		}
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		return sigv4.NewSigV4RoundTripper(c, next)
+	})
+}
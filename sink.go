@@ -0,0 +1,29 @@
+package remotewrite
+
+import (
+	"context"
+	"net/http"
+)
+
+// Payload is a single marshaled and compressed write request together with
+// the headers describing it, such as Content-Encoding, Content-Type and the
+// tenant.
+type Payload struct {
+	Body   []byte
+	Header http.Header
+}
+
+// Sink delivers payloads to a destination other than a remote write HTTP
+// endpoint, such as a message queue.
+type Sink interface {
+	Send(ctx context.Context, p Payload) error
+}
+
+// WithSink sends payloads to s instead of POSTing them to the remote write
+// URL. The URL passed to New may then be empty; set WithEndpointName to
+// label the writer's own metrics.
+func WithSink(s Sink) Option {
+	return func(c *Client) {
+		c.sink = s
+	}
+}
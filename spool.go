@@ -0,0 +1,227 @@
+package remotewrite
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// spoolExt is the file extension of spooled payloads.
+const spoolExt = ".spool"
+
+// WithSpool persists every batch that still fails after retries to a file in
+// dir, so an outage delays its delivery instead of losing it. Before each
+// send, spooled batches are sent again oldest first, and the fresh batches
+// follow once the spool has drained or a replay fails. Batches the endpoint
+// rejects permanently are not spooled, and spooled batches it later rejects
+// permanently are removed and passed to WithDeadLetter. Once the spool exceeds maxBytes, the
+// oldest batches are removed. Use a separate dir for every endpoint.
+func WithSpool(dir string, maxBytes int64) Option {
+	return func(c *Client) {
+		c.spool = &spool{dir: dir, maxBytes: maxBytes}
+	}
+}
+
+// spool stores failed payloads in a directory.
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	mu  sync.Mutex
+	seq uint64
+}
+
+// spooledPayload is the on-disk form of a spooled batch.
+type spooledPayload struct {
+	Body   []byte
+	Header http.Header
+	Series int
+}
+
+// validate reports whether the spool is usable.
+func (s *spool) validate() error {
+	switch {
+	case s.dir == "":
+		return errors.New("spool directory is required")
+	case s.maxBytes <= 0:
+		return errors.New("spool size must be positive")
+	}
+	return nil
+}
+
+// store writes p, which holds series series, to the spool and removes the
+// oldest payloads if the spool grew past its size limit.
+func (c *Client) store(p Payload, series int) error {
+	s := c.spool
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	// The name orders payloads by the time they were spooled.
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d%s", time.Now().UnixNano(), s.seq, spoolExt)
+
+	tmp, err := os.CreateTemp(s.dir, name+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create spool file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(spooledPayload{Body: p.Body, Header: p.Header, Series: series}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode spooled payload: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+
+	return c.trimSpool()
+}
+
+// spoolJobs stores the batches of jobs without sending them, if a spool is
+// configured. Batches that are not spooled are forgotten, so that the next
+// gather sends them again.
+func (c *Client) spoolJobs(jobs []batchJob) {
+	for _, job := range jobs {
+		if c.spool == nil {
+			c.forgetUnsent(job.series)
+			continue
+		}
+
+		p, err := c.encode(&prompb.WriteRequest{Timeseries: job.series}, job.tenant)
+		if err == nil {
+			err = c.store(p, len(job.series))
+		}
+		if err != nil {
+			c.logger.Error("failed to spool payload", "err", err)
+			c.forgetUnsent(job.series)
+		}
+	}
+}
+
+// trimSpool removes the oldest payloads until the spool fits its size limit.
+// It must be called with the spool locked.
+func (c *Client) trimSpool() error {
+	files, err := c.spool.files()
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+	for _, f := range files {
+		if size <= c.spool.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			return fmt.Errorf("failed to remove spooled payload: %w", err)
+		}
+		size -= f.size
+		c.metrics.spoolDropped.WithLabelValues(c.endpointName).Inc()
+		c.logger.Warn("spool full, dropped oldest payload", "path", f.path)
+	}
+	return nil
+}
+
+// drainSpool sends the spooled payloads oldest first and removes each once it
+// was delivered or rejected permanently, passing rejected ones to the dead
+// letter callback. It stops at the first payload that fails again otherwise.
+func (c *Client) drainSpool(ctx context.Context) error {
+	if c.spool == nil {
+		return nil
+	}
+
+	s := c.spool
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		sp, err := readSpooled(f.path)
+		if err != nil {
+			c.logger.Warn("removing corrupt spooled payload", "path", f.path, "err", err)
+			os.Remove(f.path)
+			continue
+		}
+
+		err = c.attempt(ctx, Payload{Body: sp.Body, Header: sp.Header}, sp.Series)
+		if code := statusCode(err); err != nil && isPermanent(code) {
+			// Sending it again cannot succeed and would hold back the
+			// payloads spooled after it.
+			c.logger.Warn("removing rejected spooled payload", "path", f.path, "err", err)
+			if c.deadLetter != nil {
+				c.deadLetter(sp.Body, code, err.Error())
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to replay spooled payload: %w", err)
+		}
+		if err := os.Remove(f.path); err != nil {
+			return fmt.Errorf("failed to remove spooled payload: %w", err)
+		}
+	}
+	return nil
+}
+
+// spoolFile is a payload in the spool.
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// files returns the spooled payloads, oldest first. os.ReadDir sorts by
+// name, which orders them by the time they were spooled.
+func (s *spool) files() ([]spoolFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var files []spoolFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), spoolExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: filepath.Join(s.dir, e.Name()), size: info.Size()})
+	}
+	return files, nil
+}
+
+func readSpooled(path string) (spooledPayload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return spooledPayload{}, err
+	}
+	defer f.Close()
+
+	var sp spooledPayload
+	err = gob.NewDecoder(f).Decode(&sp)
+	return sp, err
+}
@@ -0,0 +1,78 @@
+package remotewrite
+
+import (
+	"math"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithStalenessMarkers sends a staleness marker for every series that was
+// sent in the previous gather but is missing from the current one, as
+// Prometheus does for scraped targets. Queries then stop returning the last
+// value of a vanished series instead of carrying it forward. The label sets
+// of the last gather are kept in memory. It cannot be combined with
+// WithSeriesTTL or WithSkipDuplicateSamples, which leave out series on
+// purpose.
+func WithStalenessMarkers() Option {
+	return func(c *Client) {
+		c.staleness = &stalenessTracker{}
+	}
+}
+
+// stalenessTracker remembers the series sent in the last gather.
+type stalenessTracker struct {
+	mu       sync.Mutex
+	previous map[string][]prompb.Label
+}
+
+// markStale returns series followed by a stale marker, stamped at ts, for
+// every series of the previous gather that is not in series.
+func (t *stalenessTracker) markStale(series []prompb.TimeSeries, ts int64) []prompb.TimeSeries {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := make(map[string][]prompb.Label, len(series))
+	for _, s := range series {
+		current[seriesKey(s.Labels)] = s.Labels
+	}
+
+	for key, labels := range t.previous {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		series = append(series, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{{Value: math.Float64frombits(value.StaleNaN), Timestamp: ts}},
+		})
+	}
+
+	t.previous = current
+	return series
+}
+
+// forget records the series of the stale markers in series as sent in the
+// last gather again, so that the next gather marks them stale again if they
+// are still missing. It does nothing for a nil tracker.
+func (t *stalenessTracker) forget(series []prompb.TimeSeries) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.previous == nil {
+		t.previous = map[string][]prompb.Label{}
+	}
+	for _, ts := range series {
+		if n := len(ts.Samples); n == 0 || !value.IsStaleNaN(ts.Samples[n-1].Value) {
+			continue
+		}
+		key := seriesKey(ts.Labels)
+		if _, ok := t.previous[key]; !ok {
+			t.previous[key] = ts.Labels
+		}
+	}
+}
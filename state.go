@@ -0,0 +1,83 @@
+package remotewrite
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WithStateFile persists the per-series state kept by WithSeriesTTL to path
+// after every gather and reloads it when the Client is created, so a restart
+// does not make every series look freshly changed. A missing, unreadable or
+// corrupt file is ignored and the state starts empty.
+func WithStateFile(path string) Option {
+	return func(c *Client) {
+		c.stateFile = path
+	}
+}
+
+// persistedSeries is the on-disk form of a ttlState.
+type persistedSeries struct {
+	Value   uint64
+	Changed int64
+	Stale   bool
+}
+
+// loadState restores the series state from the state file.
+func (c *Client) loadState() {
+	if c.stateFile == "" || c.ttl == nil {
+		return
+	}
+
+	f, err := os.Open(c.stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.logger.Warn("ignoring unreadable state file", "path", c.stateFile, "err", err)
+		}
+		return
+	}
+	defer f.Close()
+
+	var series map[string]persistedSeries
+	if err := gob.NewDecoder(f).Decode(&series); err != nil {
+		c.logger.Warn("ignoring corrupt state file", "path", c.stateFile, "err", err)
+		return
+	}
+
+	for key, s := range series {
+		c.ttl.series[key] = &ttlState{value: s.Value, changed: s.Changed, stale: s.Stale}
+	}
+}
+
+// saveState writes the series state to the state file, replacing it
+// atomically.
+func (c *Client) saveState() error {
+	if c.stateFile == "" || c.ttl == nil {
+		return nil
+	}
+
+	series := make(map[string]persistedSeries, len(c.ttl.series))
+	for key, st := range c.ttl.series {
+		series[key] = persistedSeries{Value: st.value, Changed: st.changed, Stale: st.stale}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.stateFile), filepath.Base(c.stateFile)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(series); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.stateFile); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package remotewrite
+
+import (
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// tenantHeader is the header Cortex, Mimir and Loki use to identify tenants.
+const tenantHeader = "X-Scope-OrgID"
+
+// WithTenantLabel routes each series to the tenant named by its label value.
+// Series are grouped by tenant and each group is sent with the X-Scope-OrgID
+// header set to that tenant. The routing label itself is removed from the
+// payload. Series without the label go to defaultTenant; if defaultTenant is
+// empty they are sent without the header.
+func WithTenantLabel(label, defaultTenant string) Option {
+	return func(c *Client) {
+		c.tenantLabel = label
+		c.defaultTenant = defaultTenant
+	}
+}
+
+// WithTenantFunc routes each series to the tenant returned by fn for its
+// labels, which are sorted and include __name__. Series are grouped by tenant
+// and each group is sent with the X-Scope-OrgID header set to that tenant.
+// Unlike WithTenantLabel, the labels are sent unchanged. Series for which fn
+// returns "" go to defaultTenant; if defaultTenant is empty they are sent
+// without the header.
+func WithTenantFunc(fn func(labels []prompb.Label) string, defaultTenant string) Option {
+	return func(c *Client) {
+		c.tenantFunc = fn
+		c.defaultTenant = defaultTenant
+	}
+}
+
+// tenantSeries is the set of series destined for a single tenant.
+type tenantSeries struct {
+	tenant string
+	series []prompb.TimeSeries
+}
+
+// partitionByTenant groups series by tenant, in order of first appearance.
+func (c *Client) partitionByTenant(series []prompb.TimeSeries) []tenantSeries {
+	if c.tenantLabel == "" && c.tenantFunc == nil {
+		return []tenantSeries{{tenant: c.defaultTenant, series: series}}
+	}
+
+	var (
+		parts []tenantSeries
+		index = map[string]int{}
+	)
+	for _, ts := range series {
+		var tenant string
+		if c.tenantFunc != nil {
+			tenant = c.tenantFunc(ts.Labels)
+		} else {
+			tenant, ts.Labels = c.takeTenantLabel(ts.Labels)
+		}
+		if tenant == "" {
+			tenant = c.defaultTenant
+		}
+
+		i, ok := index[tenant]
+		if !ok {
+			i = len(parts)
+			index[tenant] = i
+			parts = append(parts, tenantSeries{tenant: tenant})
+		}
+		parts[i].series = append(parts[i].series, ts)
+	}
+
+	return parts
+}
+
+// takeTenantLabel returns the value of the tenant label and labels without
+// it.
+func (c *Client) takeTenantLabel(labels []prompb.Label) (string, []prompb.Label) {
+	var tenant string
+	out := labels[:0:0]
+	for _, l := range labels {
+		if l.Name == c.tenantLabel {
+			tenant = l.Value
+			continue
+		}
+		out = append(out, l)
+	}
+	return tenant, out
+}
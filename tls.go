@@ -0,0 +1,105 @@
+package remotewrite
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// tlsOptions holds the TLS settings applied to the default transport.
+type tlsOptions struct {
+	caFile             string
+	certPool           *x509.CertPool
+	certFile           string
+	keyFile            string
+	insecureSkipVerify bool
+}
+
+func (o tlsOptions) set() bool {
+	return o.caFile != "" || o.certPool != nil || o.certFile != "" || o.keyFile != "" || o.insecureSkipVerify
+}
+
+// WithTLSCAFile verifies the endpoint's certificate against the PEM-encoded
+// CA certificates in path instead of the system roots.
+func WithTLSCAFile(path string) Option {
+	return func(c *Client) {
+		c.tls.caFile = path
+	}
+}
+
+// WithTLSCertPool verifies the endpoint's certificate against pool instead
+// of the system roots.
+func WithTLSCertPool(pool *x509.CertPool) Option {
+	return func(c *Client) {
+		c.tls.certPool = pool
+	}
+}
+
+// WithTLSClientCert presents the PEM-encoded certificate and key in certFile
+// and keyFile to the endpoint, for mutual TLS.
+func WithTLSClientCert(certFile, keyFile string) Option {
+	return func(c *Client) {
+		c.tls.certFile = certFile
+		c.tls.keyFile = keyFile
+	}
+}
+
+// WithTLSInsecureSkipVerify disables verification of the endpoint's
+// certificate chain and host name. This makes the connection vulnerable to
+// man-in-the-middle attacks and should only be used for testing.
+func WithTLSInsecureSkipVerify() Option {
+	return func(c *Client) {
+		c.tls.insecureSkipVerify = true
+	}
+}
+
+// validate checks the TLS options that can be checked without reading files.
+func (o tlsOptions) validate() error {
+	var errs []error
+	if o.caFile != "" && o.certPool != nil {
+		errs = append(errs, errors.New("TLS CA file and cert pool are mutually exclusive"))
+	}
+	if (o.certFile == "") != (o.keyFile == "") {
+		errs = append(errs, errors.New("TLS client certificate and key must be set together"))
+	}
+	return errors.Join(errs...)
+}
+
+// apply returns a copy of base, or a new config if base is nil, with the
+// options applied.
+func (o tlsOptions) apply(base *tls.Config) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if base != nil {
+		cfg = base.Clone()
+	}
+
+	if o.caFile != "" {
+		pem, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in TLS CA file %s", o.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.certPool != nil {
+		cfg.RootCAs = o.certPool
+	}
+
+	if o.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if o.insecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+	return cfg, nil
+}
@@ -0,0 +1,135 @@
+package remotewrite
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// WithHTTPClient sets the HTTP client used to send requests, for example to
+// reuse one shared across the application. By default the Client builds its
+// own from a clone of http.DefaultTransport, which honors the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables, and reuses it for every
+// send.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// DefaultTimeout is the per-request timeout used unless WithTimeout is set.
+const DefaultTimeout = 30 * time.Second
+
+// WithTimeout bounds how long a single request to the endpoint or sink may
+// take. It applies to each attempt separately, including retries. It
+// defaults to DefaultTimeout; zero disables the timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithTransport builds the Client's HTTP client from a clone of t instead of
+// http.DefaultTransport, for example to set an explicit Proxy. Other
+// transport options, such as WithDialNetwork, are applied on top of it.
+func WithTransport(t *http.Transport) Option {
+	return func(c *Client) {
+		c.transport = t
+	}
+}
+
+// WithDialNetwork forces the network used to dial the remote write endpoint:
+// "tcp4" for IPv4 only, "tcp6" for IPv6 only, or "tcp" to let the resolver
+// choose, which is the default.
+func WithDialNetwork(network string) Option {
+	return func(c *Client) {
+		c.dialNetwork = network
+	}
+}
+
+// WithHTTP2 sends requests over HTTP/2 for endpoints that support it. Over
+// https the protocol is negotiated with TLS, and it composes with
+// WithTransport, WithDialNetwork and the TLS options. Over plain http the
+// Client speaks HTTP/2 without TLS (h2c), so the endpoint must accept it; h2c
+// does not use a proxy, composes with WithDialNetwork only, and cannot be
+// combined with WithTransport. The default is HTTP/1.1, unless a transport
+// set with WithTransport enables HTTP/2 itself.
+func WithHTTP2() Option {
+	return func(c *Client) {
+		c.http2 = true
+	}
+}
+
+// newHTTPClient builds the HTTP client used for sends.
+func (c *Client) newHTTPClient() (*http.Client, error) {
+	base := c.transport
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+
+	if c.dialNetwork != "" {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		network := c.dialNetwork
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+
+	if c.tls.set() {
+		cfg, err := c.tls.apply(transport.TLSClientConfig)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = cfg
+	}
+
+	if c.http2 {
+		return c.newHTTP2Client(transport)
+	}
+	if c.transport == nil {
+		// http.DefaultTransport attempts HTTP/2 over TLS; an empty
+		// TLSNextProto keeps the Client on HTTP/1.1.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if cfg := transport.TLSClientConfig; cfg != nil {
+			// A transport that already spoke HTTP/2 advertises h2.
+			cfg = cfg.Clone()
+			cfg.NextProtos = slices.DeleteFunc(slices.Clone(cfg.NextProtos), func(p string) bool { return p == "h2" })
+			transport.TLSClientConfig = cfg
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newHTTP2Client builds an HTTP/2 client from transport.
+func (c *Client) newHTTP2Client(transport *http.Transport) (*http.Client, error) {
+	if u, err := url.Parse(c.url); err == nil && u.Scheme == "http" {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		return &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}}, nil
+	}
+
+	if _, err := http2.ConfigureTransports(transport); err != nil {
+		return nil, fmt.Errorf("failed to configure HTTP/2: %w", err)
+	}
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,75 @@
+package remotewrite
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// WithSeriesTTL stops sending gauge series whose value has not changed for
+// longer than ttl. When a series expires a single staleness marker is sent
+// for it; sending resumes as soon as its value changes again.
+func WithSeriesTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.ttl = newSeriesTTL(ttl)
+	}
+}
+
+// seriesTTL tracks when each gauge series last changed value.
+type seriesTTL struct {
+	ttl        int64 // milliseconds
+	series     map[string]*ttlState
+	generation uint64
+}
+
+type ttlState struct {
+	value      uint64 // float64 bits, so NaN compares equal to itself
+	changed    int64  // milliseconds
+	stale      bool
+	generation uint64
+}
+
+func newSeriesTTL(ttl time.Duration) *seriesTTL {
+	return &seriesTTL{
+		ttl:    ttl.Milliseconds(),
+		series: map[string]*ttlState{},
+	}
+}
+
+// begin starts tracking a new gather.
+func (t *seriesTTL) begin() {
+	t.generation++
+}
+
+// apply reports whether the sample of the series identified by key should be
+// sent. When the series expires, s is replaced by a staleness marker.
+func (t *seriesTTL) apply(key string, s *prompb.Sample) bool {
+	bits := math.Float64bits(s.Value)
+
+	st, ok := t.series[key]
+	if !ok || st.value != bits {
+		t.series[key] = &ttlState{value: bits, changed: s.Timestamp, generation: t.generation}
+		return true
+	}
+	st.generation = t.generation
+
+	if st.stale {
+		return false
+	}
+	if s.Timestamp-st.changed > t.ttl {
+		st.stale = true
+		s.Value = math.Float64frombits(value.StaleNaN)
+	}
+	return true
+}
+
+// sweep forgets series that were not part of the current gather.
+func (t *seriesTTL) sweep() {
+	for key, st := range t.series {
+		if st.generation != t.generation {
+			delete(t.series, key)
+		}
+	}
+}
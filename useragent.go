@@ -0,0 +1,31 @@
+package remotewrite
+
+import "runtime/debug"
+
+const modulePath = "github.com/pree-dew/prometheus-remote-write"
+
+// DefaultUserAgent is the User-Agent sent unless WithUserAgent is set. It
+// carries the module version when it is known from the build information.
+var DefaultUserAgent = "prometheus-remote-write/" + moduleVersion()
+
+// WithUserAgent overrides the User-Agent header sent to the endpoint.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// moduleVersion returns the version of this module in the running binary, or
+// "devel" if it cannot be determined.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath && dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return "devel"
+}
@@ -0,0 +1,194 @@
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Validate reports malformed or conflicting configuration. New calls it, so
+// a Client returned by New is always valid.
+func (c *Client) Validate() error {
+	var errs []error
+
+	u, err := url.Parse(c.url)
+	switch {
+	case c.sink != nil:
+		// The URL is not used when sending to a sink.
+	case c.url == "":
+		errs = append(errs, errors.New("remote write URL is required"))
+	case err != nil:
+		errs = append(errs, fmt.Errorf("invalid remote write URL: %w", err))
+	case u.Scheme != "http" && u.Scheme != "https":
+		errs = append(errs, fmt.Errorf("remote write URL %q must use http or https", u.Redacted()))
+	case u.Host == "":
+		errs = append(errs, fmt.Errorf("remote write URL %q has no host", u.Redacted()))
+	}
+
+	switch c.dialNetwork {
+	case "", "tcp", "tcp4", "tcp6":
+	default:
+		errs = append(errs, fmt.Errorf("dial network must be tcp, tcp4 or tcp6, got %q", c.dialNetwork))
+	}
+	if c.dialNetwork != "" && c.httpClient != nil {
+		errs = append(errs, errors.New("dial network cannot be combined with a custom HTTP client"))
+	}
+	if c.transport != nil && c.httpClient != nil {
+		errs = append(errs, errors.New("transport cannot be combined with a custom HTTP client"))
+	}
+	if c.http2 && c.httpClient != nil {
+		errs = append(errs, errors.New("HTTP/2 cannot be combined with a custom HTTP client"))
+	}
+	if c.http2 && c.transport != nil && err == nil && u.Scheme == "http" {
+		// The h2c transport cannot honor the proxy and timeouts of t.
+		errs = append(errs, errors.New("HTTP/2 over plain http cannot be combined with a custom transport"))
+	}
+	if c.tls.set() && c.httpClient != nil {
+		errs = append(errs, errors.New("TLS options cannot be combined with a custom HTTP client"))
+	}
+	if err := c.tls.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.clock != nil && c.monotonic {
+		errs = append(errs, errors.New("clock cannot be combined with monotonic timestamps"))
+	}
+
+	if c.timeout < 0 {
+		errs = append(errs, errors.New("timeout must not be negative"))
+	}
+
+	if err := c.validateHeaders(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.authSchemes() > 1 {
+		errs = append(errs, errors.New("only one authentication scheme may be configured"))
+	}
+	if c.oauth2 != nil {
+		if err := c.oauth2.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch c.compression {
+	case CompressionSnappy, CompressionSnappyFramed, CompressionGzip, CompressionNone:
+	case CompressionZstd:
+		if c.zstdLevel < zstd.SpeedFastest || c.zstdLevel > zstd.SpeedBestCompression {
+			errs = append(errs, fmt.Errorf("invalid zstd level %d", c.zstdLevel))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown compression %d", c.compression))
+	}
+
+	if c.protocol != ProtocolV1 && c.protocol != ProtocolV2 {
+		errs = append(errs, fmt.Errorf("unknown protocol version %d", c.protocol))
+	}
+
+	if c.seriesOrder != SeriesOrderPrometheus && c.seriesOrder != SeriesOrderOpenMetrics {
+		errs = append(errs, fmt.Errorf("unknown series order %d", c.seriesOrder))
+	}
+
+	if c.sumType != SumTypeCounter && c.sumType != SumTypeGauge {
+		errs = append(errs, fmt.Errorf("unknown sum type %d", c.sumType))
+	}
+
+	for _, p := range []EmptyGatherPolicy{c.emptyStartup, c.emptyRuntime} {
+		if p < EmptyGatherSkip || p > EmptyGatherError {
+			errs = append(errs, fmt.Errorf("unknown empty gather policy %d", p))
+		}
+	}
+
+	if c.logger == nil {
+		errs = append(errs, errors.New("logger must not be nil"))
+	}
+	if c.gatherer == nil {
+		errs = append(errs, errors.New("gatherer must not be nil"))
+	}
+	if c.splitter == nil {
+		errs = append(errs, errors.New("batch splitter must not be nil"))
+	}
+
+	if c.maxBatchBytes < 0 {
+		errs = append(errs, errors.New("max batch bytes must not be negative"))
+	}
+	if c.adaptiveBatching && c.minBatchSeries < 1 {
+		errs = append(errs, errors.New("minimum adaptive batch size must be at least 1"))
+	}
+
+	if c.shards < 1 {
+		errs = append(errs, errors.New("number of shards must be at least 1"))
+	}
+	if c.adaptiveShards && c.maxShards < c.minShards {
+		errs = append(errs, errors.New("maximum shards must not be below minimum shards"))
+	}
+
+	if c.ttl != nil && c.ttl.ttl <= 0 {
+		errs = append(errs, errors.New("series TTL must be positive"))
+	}
+	if c.stateFile != "" && c.ttl == nil {
+		errs = append(errs, errors.New("state file requires a series TTL"))
+	}
+
+	if c.labelCountThreshold < 0 || c.labelCountLimit < 0 {
+		errs = append(errs, errors.New("label count limits must not be negative"))
+	}
+	if c.maxLabelNameLength < 0 || c.maxLabelValueLength < 0 {
+		errs = append(errs, errors.New("label length limits must not be negative"))
+	}
+	if c.labelLimitAction != LabelLimitDrop && c.labelLimitAction != LabelLimitTruncate {
+		errs = append(errs, fmt.Errorf("unknown label limit action %d", c.labelLimitAction))
+	}
+
+	if c.breaker != nil && (c.breaker.threshold < 1 || c.breaker.cooldown <= 0) {
+		errs = append(errs, errors.New("circuit breaker needs at least 1 failure and a positive cooldown"))
+	}
+	if c.spool != nil {
+		if err := c.spool.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.quota != nil && (c.quota.limit <= 0 || c.quota.window <= 0) {
+		errs = append(errs, errors.New("sample quota and window must be positive"))
+	}
+
+	switch {
+	case c.maxBufferBytes < 0:
+		errs = append(errs, errors.New("maximum buffer bytes must not be negative"))
+	case c.maxBufferBytes > 0 && c.backpressure != BackpressureQueue:
+		errs = append(errs, errors.New("maximum buffer bytes requires the BackpressureQueue policy"))
+	}
+
+	switch c.backpressure {
+	case BackpressureSkip:
+	case BackpressureQueue:
+		if c.maxQueued < 1 {
+			errs = append(errs, errors.New("maximum queued gathers must be at least 1"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown backpressure policy %d", c.backpressure))
+	}
+
+	if c.staleness != nil && (c.ttl != nil || c.duplicates != nil) {
+		errs = append(errs, errors.New("staleness markers cannot be combined with series TTLs or skipping duplicate samples"))
+	}
+
+	if c.tenantLabel != "" && c.tenantFunc != nil {
+		errs = append(errs, errors.New("tenant label cannot be combined with a tenant function"))
+	}
+	if c.tenantLabel == "__name__" {
+		errs = append(errs, errors.New("tenant label must not be __name__"))
+	}
+
+	if c.nameValidation < NameValidationNone || c.nameValidation > NameValidationStrict {
+		errs = append(errs, fmt.Errorf("unknown name validation %d", c.nameValidation))
+	}
+	if err := c.validateNameOptions(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}